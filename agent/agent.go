@@ -0,0 +1,333 @@
+package agent
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/log"
+	"github.com/bobwong89757/cellnet/msglog"
+	"github.com/bobwong89757/cellnet/relay"
+	"github.com/bobwong89757/cellnet/rpc"
+)
+
+// routeRule 客户端消息的路由规则
+type routeRule struct {
+	// minID, maxID 消息 ID 范围，包含两端，contextKey 为空时使用
+	minID, maxID int
+
+	// service ID 范围匹配时转发到的服务名
+	service string
+
+	// contextKey 从消息元信息的上下文中读取服务名的键，为空时按 ID 范围匹配
+	contextKey string
+}
+
+// match 匹配消息，返回转发到的服务名，不匹配时返回空字符串
+func (self *routeRule) match(meta *cellnet.MessageMeta) string {
+	if self.contextKey != "" {
+		return meta.GetContextAsString(self.contextKey, "")
+	}
+
+	if meta.ID >= self.minID && meta.ID <= self.maxID {
+		return self.service
+	}
+
+	return ""
+}
+
+// backendService 一个后端服务的所有副本
+type backendService struct {
+	// pool 后端连接器，按客户端 Session ID 选择副本，同一个客户端的消息总是发往同一个副本
+	pool *rpc.ClientPool
+
+	// removeByPeer 按连接器存储注销广播函数的函数
+	removeByPeer map[cellnet.Peer]func()
+}
+
+// Agent 网关
+// 接受客户端连接（tcp、ws、kcp 等 Acceptor），按规则将客户端消息通过 relay 转发到后端服务，透传客户端的 Session ID
+// 后端回复和广播的 relay 消息按透传的 Session ID 发回对应的客户端
+// 客户端断开时，向它访问过的后端发送 ClientClosedACK
+//
+// 使用示例:
+//
+//	gate := agent.NewAgent(clientAcceptor)
+//	gate.RouteIDRange(1000, 1999, "login")
+//	gate.RouteByContext("service") // 消息元信息上下文 service=battle 时转发到 battle 服务
+//	gate.AddBackend("login", loginConnector)
+//	gate.AddBackend("battle", battleConnector1, battleConnector2)
+//
+//	proc.BindProcessorHandler(clientAcceptor, "tcp.ltv", gate.ClientCallback(func(ev cellnet.Event) {
+//		// 没有匹配路由的消息和系统事件
+//	}))
+type Agent struct {
+	// frontend 接受客户端连接的 Peer
+	frontend cellnet.Peer
+
+	// guard 保护以下字段
+	guard sync.RWMutex
+
+	// rules 按添加顺序匹配的路由规则
+	rules []routeRule
+
+	// serviceByName 按服务名存储后端服务
+	serviceByName map[string]*backendService
+
+	// backendsByClient 按客户端 Session ID 存储客户端访问过的后端 Session，断开时通知
+	backendsByClient map[int64]map[cellnet.Session]struct{}
+}
+
+// RouteIDRange 添加按消息 ID 范围的路由规则
+// minID, maxID: 消息 ID 范围，包含两端
+// service: 转发到的服务名
+// 规则按添加顺序匹配，第一条匹配的规则生效
+func (self *Agent) RouteIDRange(minID, maxID int, service string) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.rules = append(self.rules, routeRule{minID: minID, maxID: maxID, service: service})
+}
+
+// RouteByContext 添加按消息元信息上下文的路由规则
+// key: 上下文的键，值为转发到的服务名，通过 MessageMeta.SetContext 设置，如 SetContext("service", "battle")
+// 没有设置此上下文的消息不匹配
+// 规则按添加顺序匹配，第一条匹配的规则生效
+func (self *Agent) RouteByContext(key string) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.rules = append(self.rules, routeRule{contextKey: key})
+}
+
+// AddBackend 添加后端服务的连接器
+// service: 服务名
+// peers: 连接到后端服务的连接器，需要绑定包含 relay 的处理器（如 tcp.ltv），提供 Session() 方法
+// 同一个服务有多个连接器时，按客户端 Session ID 选择，同一个客户端的消息总是发往同一个已就绪的副本
+// 连接器收到的 relay 消息如果指定了客户端，转发给客户端后不再交给连接器的用户回调
+func (self *Agent) AddBackend(service string, peers ...cellnet.Peer) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	svc := self.serviceByName[service]
+	if svc == nil {
+		svc = &backendService{
+			pool:         rpc.NewClientPool(rpc.ConsistentHashStrategy{}),
+			removeByPeer: map[cellnet.Peer]func(){},
+		}
+
+		self.serviceByName[service] = svc
+	}
+
+	for _, p := range peers {
+		if _, ok := svc.removeByPeer[p]; ok {
+			continue
+		}
+
+		svc.pool.Add(p)
+		svc.removeByPeer[p] = relay.AddBroadcaster(p, self.onBackendMessage)
+	}
+}
+
+// RemoveBackend 移除后端服务的连接器
+// service: 服务名
+// p: 要移除的连接器，只从网关中移除，不会停止连接器
+func (self *Agent) RemoveBackend(service string, p cellnet.Peer) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	svc := self.serviceByName[service]
+	if svc == nil {
+		return
+	}
+
+	if remove, ok := svc.removeByPeer[p]; ok {
+		remove()
+		delete(svc.removeByPeer, p)
+		svc.pool.Remove(p)
+	}
+}
+
+// ClientCallback 创建客户端 Peer 的事件回调
+// userCallback: 用户回调，接收没有匹配路由的消息和所有系统事件，可以为 nil
+// 匹配路由的消息转发到后端服务，不再交给用户回调
+// 客户端断开时，先通知访问过的后端，再交给用户回调
+func (self *Agent) ClientCallback(userCallback cellnet.EventCallback) cellnet.EventCallback {
+	return func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionClosed:
+			self.onClientClosed(ev.Session())
+		default:
+			if self.routeClientMessage(ev) {
+				return
+			}
+		}
+
+		if userCallback != nil {
+			userCallback(ev)
+		}
+	}
+}
+
+// routeClientMessage 按路由规则转发客户端消息
+// 返回消息是否匹配路由规则
+// 连接建立、断开等系统事件（cellnet.SystemMessageIdentifier）不参与路由，即使消息 ID 落在路由范围内
+func (self *Agent) routeClientMessage(ev cellnet.Event) bool {
+	if _, ok := ev.Message().(cellnet.SystemMessageIdentifier); ok {
+		return false
+	}
+
+	meta := cellnet.MessageMetaByMsg(ev.Message())
+	if meta == nil {
+		return false
+	}
+
+	service := self.matchService(meta)
+	if service == "" {
+		return false
+	}
+
+	clientSes := ev.Session()
+
+	backendSes := self.pickBackend(service, clientSes.ID())
+	if backendSes == nil {
+		log.GetLog().Errorf("#agent.route no backend for service '%s', msg: %s, client: %d", service, meta.TypeName(), clientSes.ID())
+		return true
+	}
+
+	if err := relay.Relay(backendSes, ev.Message(), clientSes.ID()); err != nil {
+		log.GetLog().Errorf("#agent.route relay failed, msg: %s, client: %d, err: %s", meta.TypeName(), clientSes.ID(), err)
+		return true
+	}
+
+	self.guard.Lock()
+	backends := self.backendsByClient[clientSes.ID()]
+	if backends == nil {
+		backends = map[cellnet.Session]struct{}{}
+		self.backendsByClient[clientSes.ID()] = backends
+	}
+	backends[backendSes] = struct{}{}
+	self.guard.Unlock()
+
+	if msglog.IsMsgLogValid(meta.ID) {
+		log.GetLog().Debugf("#agent.recv(%s)@%d -> %s len: %d %s | %s",
+			service,
+			clientSes.ID(),
+			backendSes.Peer().(cellnet.PeerProperty).Name(),
+			cellnet.MessageSize(ev.Message()),
+			meta.TypeName(),
+			cellnet.MessageToString(ev.Message()))
+	}
+
+	return true
+}
+
+// matchService 按路由规则匹配服务名，不匹配时返回空字符串
+func (self *Agent) matchService(meta *cellnet.MessageMeta) string {
+	self.guard.RLock()
+	defer self.guard.RUnlock()
+
+	for index := range self.rules {
+		if service := self.rules[index].match(meta); service != "" {
+			return service
+		}
+	}
+
+	return ""
+}
+
+// pickBackend 为客户端选择后端服务的 Session，服务不存在或没有就绪的副本时返回 nil
+func (self *Agent) pickBackend(service string, clientID int64) cellnet.Session {
+	self.guard.RLock()
+	svc := self.serviceByName[service]
+	self.guard.RUnlock()
+
+	if svc == nil {
+		return nil
+	}
+
+	return svc.pool.Pick(strconv.FormatInt(clientID, 10))
+}
+
+// onClientClosed 客户端断开时通知访问过的后端
+func (self *Agent) onClientClosed(clientSes cellnet.Session) {
+	self.guard.Lock()
+	backends := self.backendsByClient[clientSes.ID()]
+	delete(self.backendsByClient, clientSes.ID())
+	self.guard.Unlock()
+
+	for backendSes := range backends {
+		backendSes.Send(&ClientClosedACK{ID: clientSes.ID()})
+	}
+}
+
+// onBackendMessage 处理后端发来的 relay 消息（relay.PeerBroadcasterFunc）
+// 指定了客户端的消息发给客户端，返回 false 不再交给连接器的用户回调
+func (self *Agent) onBackendMessage(ev *relay.RecvMsgEvent) bool {
+	if _, ok := ev.HeaderInt64(headerBroadcast); ok {
+		self.frontend.(cellnet.SessionAccessor).VisitSession(func(clientSes cellnet.Session) bool {
+			self.sendToClient(clientSes, ev.Msg)
+			return true
+		})
+
+		return false
+	}
+
+	if clientIDs := ev.PassThroughAsInt64Slice(); len(clientIDs) > 0 {
+		for _, clientID := range clientIDs {
+			self.sendToClientID(clientID, ev.Msg)
+		}
+
+		return false
+	}
+
+	if clientID := ev.PassThroughAsInt64(); clientID != 0 {
+		self.sendToClientID(clientID, ev.Msg)
+		return false
+	}
+
+	return true
+}
+
+// sendToClientID 发送消息给指定 Session ID 的客户端，客户端已断开时忽略
+func (self *Agent) sendToClientID(clientID int64, msg interface{}) {
+	clientSes := self.frontend.(cellnet.SessionAccessor).GetSession(clientID)
+	if clientSes == nil {
+		return
+	}
+
+	self.sendToClient(clientSes, msg)
+}
+
+// sendToClient 发送消息给客户端
+func (self *Agent) sendToClient(clientSes cellnet.Session, msg interface{}) {
+	if msg == nil {
+		return
+	}
+
+	if msglog.IsMsgLogValid(cellnet.MessageToID(msg)) {
+		log.GetLog().Debugf("#agent.send(%s)@%d len: %d %s | %s",
+			self.frontend.(cellnet.PeerProperty).Name(),
+			clientSes.ID(),
+			cellnet.MessageSize(msg),
+			cellnet.MessageToName(msg),
+			cellnet.MessageToString(msg))
+	}
+
+	clientSes.Send(msg)
+}
+
+// NewAgent 创建网关
+// frontend: 接受客户端连接的 Peer，需要实现 cellnet.SessionAccessor，如 tcp.Acceptor、ws.Acceptor、kcp.Acceptor
+// 如果 frontend 没有实现 cellnet.SessionAccessor，会触发 panic
+func NewAgent(frontend cellnet.Peer) *Agent {
+	if _, ok := frontend.(cellnet.SessionAccessor); !ok {
+		panic("agent: frontend peer not implement cellnet.SessionAccessor")
+	}
+
+	return &Agent{
+		frontend:         frontend,
+		serviceByName:    map[string]*backendService{},
+		backendsByClient: map[int64]map[cellnet.Session]struct{}{},
+	}
+}
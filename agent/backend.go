@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"github.com/bobwong89757/cellnet/relay"
+)
+
+// headerBroadcast 透传头部中标记广播给所有客户端的键
+const headerBroadcast = "agent.broadcast"
+
+// ClientID 获取网关转发的消息对应的客户端 Session ID
+// ev: 后端收到的 relay 消息事件
+// 通过 ev.Reply 回复时，网关将回复发给此客户端
+func ClientID(ev *relay.RecvMsgEvent) int64 {
+	return ev.PassThroughAsInt64()
+}
+
+// SendToClient 后端通过网关发送消息给客户端
+// backend: 连接到网关的 Session
+// clientID: 客户端 Session ID，通过 ClientID 获取
+// msg: 发给客户端的消息
+func SendToClient(backend interface{}, clientID int64, msg interface{}) error {
+	return relay.Relay(backend, msg, clientID)
+}
+
+// SendToClients 后端通过网关发送消息给多个客户端
+// backend: 连接到网关的 Session
+// clientIDs: 客户端 Session ID 列表
+// msg: 发给客户端的消息
+func SendToClients(backend interface{}, clientIDs []int64, msg interface{}) error {
+	if len(clientIDs) == 0 {
+		return nil
+	}
+
+	return relay.Relay(backend, msg, clientIDs)
+}
+
+// Broadcast 后端通过网关发送消息给网关的所有客户端
+// backend: 连接到网关的 Session
+// msg: 发给客户端的消息
+func Broadcast(backend interface{}, msg interface{}) error {
+	return relay.Relay(backend, msg, relay.NewHeader().SetInt64(headerBroadcast, 1))
+}
@@ -0,0 +1,59 @@
+// Generated by github.com/bobwong89757/protoplus
+// DO NOT EDIT!
+package agent
+
+import (
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+	_ "github.com/bobwong89757/cellnet/codec/protoplus"
+	"github.com/bobwong89757/protoplus/proto"
+	"reflect"
+	"unsafe"
+)
+
+var (
+	_ *proto.Buffer
+	_ codec.CodecRecycler
+	_ cellnet.Session
+	_ reflect.Type
+	_ unsafe.Pointer
+)
+
+type ClientClosedACK struct {
+	ID int64 // 客户端Session ID
+}
+
+func (self *ClientClosedACK) String() string { return proto.CompactTextString(self) }
+
+func (self *ClientClosedACK) Size() (ret int) {
+
+	ret += proto.SizeInt64(0, self.ID)
+
+	return
+}
+
+func (self *ClientClosedACK) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalInt64(buffer, 0, self.ID)
+
+	return nil
+}
+
+func (self *ClientClosedACK) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalInt64(buffer, wt, &self.ID)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+func init() {
+
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*ClientClosedACK)(nil)).Elem(),
+		ID:    33611,
+	})
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/bobwong89757/cellnet"
@@ -54,7 +55,7 @@ func client() {
 
 	queue.StartLoop()
 
-	rv.WaitMessage("cellnet.SessionConnected")
+	rv.WaitMessage(context.Background(), "cellnet.SessionConnected")
 
 	p.(cellnet.TCPConnector).Session().Send(&TestEchoACK{
 		Msg:   "hello",
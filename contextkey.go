@@ -0,0 +1,110 @@
+package cellnet
+
+// ContextKey 类型安全的上下文数据键
+// 用于在任意 ContextSet（Peer、Session 等）上存取固定类型的上下文数据
+// 键的唯一性由 ContextKey 实例（指针）保证，不同实例即使名称相同也不会冲突
+//
+// 使用示例:
+//
+//	var UserIDKey = cellnet.NewContextKey[int64]("userid")
+//
+//	UserIDKey.Set(ses.(cellnet.ContextSet), 10086)
+//	uid, ok := UserIDKey.Get(ses.(cellnet.ContextSet))
+type ContextKey[T any] struct {
+	// name 键的名称，仅用于调试和日志
+	name string
+}
+
+// NewContextKey 创建一个类型安全的上下文数据键
+// name: 键的名称，仅用于调试和日志
+// 通常定义为包级变量，在整个进程中复用
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+// Name 返回键的名称
+func (self *ContextKey[T]) Name() string {
+	return self.name
+}
+
+// String 返回键的字符串表示
+func (self *ContextKey[T]) String() string {
+	return "ContextKey(" + self.name + ")"
+}
+
+// Get 从 ContextSet 获取上下文数据
+// cs: 上下文数据集合
+// 返回上下文数据和是否存在
+// 如果键不存在或值的类型不是 T，返回零值和 false
+func (self *ContextKey[T]) Get(cs ContextSet) (ret T, ok bool) {
+	raw, exists := cs.GetContext(self)
+	if !exists {
+		return
+	}
+
+	// nil 值视为 T 的零值
+	if raw == nil {
+		return ret, true
+	}
+
+	ret, ok = raw.(T)
+	return
+}
+
+// Value 从 ContextSet 获取上下文数据，不存在时返回 defaultValue
+// cs: 上下文数据集合
+// defaultValue: 键不存在时返回的默认值
+func (self *ContextKey[T]) Value(cs ContextSet, defaultValue T) T {
+	if v, ok := self.Get(cs); ok {
+		return v
+	}
+
+	return defaultValue
+}
+
+// Set 设置上下文数据
+// cs: 上下文数据集合
+// v: 上下文数据的值
+func (self *ContextKey[T]) Set(cs ContextSet, v T) {
+	cs.SetContext(self, v)
+}
+
+// Delete 删除上下文数据
+// cs: 上下文数据集合
+func (self *ContextKey[T]) Delete(cs ContextSet) {
+	cs.DeleteContext(self)
+}
+
+// contextInitializer 支持原子获取或创建上下文数据的 ContextSet
+// peer.CoreContextSet 实现了此接口
+type contextInitializer interface {
+	GetOrInitContext(key interface{}, init func() interface{}) (interface{}, bool)
+}
+
+// GetOrInit 获取上下文数据，不存在时使用 init 创建并保存
+// cs: 上下文数据集合
+// init: 创建上下文数据的函数
+// 返回已存在或新创建的上下文数据
+// 如果 cs 支持原子操作（如 peer.CoreContextSet），并发调用时 init 只会被调用一次，此时 init 中不能再访问 cs
+func (self *ContextKey[T]) GetOrInit(cs ContextSet, init func() T) T {
+	if ci, ok := cs.(contextInitializer); ok {
+		raw, _ := ci.GetOrInitContext(self, func() interface{} {
+			return init()
+		})
+
+		if v, ok := raw.(T); ok {
+			return v
+		}
+
+		var zero T
+		return zero
+	}
+
+	if v, ok := self.Get(cs); ok {
+		return v
+	}
+
+	v := init()
+	cs.SetContext(self, v)
+	return v
+}
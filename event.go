@@ -33,6 +33,35 @@ func (self *RecvMsgEvent) Reply(msg interface{}) {
 	self.Ses.Send(msg)
 }
 
+// EventBatch 由多个事件组成的事件
+// 钩子需要将一个网络消息展开为多个事件时返回 EventBatch
+// Peer 按顺序将其中的每个事件分别交给用户回调
+type EventBatch interface {
+	Event
+
+	// Events 返回展开后的事件列表
+	Events() []Event
+}
+
+// ConsumableEvent 可以在交给用户回调之前被处理完毕的事件
+// 钩子在队列中继续处理事件（如 relay 广播函数）并将其标记为已处理后，事件不再交给用户回调
+type ConsumableEvent interface {
+	Event
+
+	// Consumed 事件是否已经处理完毕
+	Consumed() bool
+}
+
+// IsEventConsumed 检查事件是否已经处理完毕，不再需要交给用户回调
+// 没有实现 ConsumableEvent 的事件返回 false
+func IsEventConsumed(ev Event) bool {
+	if consumable, ok := ev.(ConsumableEvent); ok {
+		return consumable.Consumed()
+	}
+
+	return false
+}
+
 // SendMsgEvent 表示会话开始发送数据的事件
 // 当调用 Session.Send() 发送消息时，会创建此事件
 // 此事件会经过 EventHooker 处理，然后由 MessageTransmitter 发送
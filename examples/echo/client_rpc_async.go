@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/peer"
@@ -27,7 +28,13 @@ func clientAsyncRPC() {
 	queue.StartLoop()
 
 	// 等连接上时
-	rv.WaitMessage("cellnet.SessionConnected")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if _, err := rv.WaitMessage(ctx, "cellnet.SessionConnected"); err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	// 异步RPC
 	rpc.Call(p, &TestEchoACK{
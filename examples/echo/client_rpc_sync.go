@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/peer"
 	"github.com/bobwong89757/cellnet/proc"
@@ -24,7 +26,13 @@ func clientSyncRPC() {
 	queue.StartLoop()
 
 	// 等连接上时
-	rv.WaitMessage("cellnet.SessionConnected")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if _, err := rv.WaitMessage(ctx, "cellnet.SessionConnected"); err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	// 同步RPC
 	rpc.CallSync(p, &TestEchoACK{
@@ -81,6 +81,11 @@ type ContextSet interface {
 	// valuePtr: 指向目标值的指针，类型会自动匹配
 	// 返回是否成功获取并设置
 	FetchContext(key, valuePtr interface{}) bool
+
+	// DeleteContext 从对象上删除一个自定义属性
+	// key: 属性的键
+	// 如果键不存在，不做任何处理
+	DeleteContext(key interface{})
 }
 
 // SessionAccessor 提供会话访问接口
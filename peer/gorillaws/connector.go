@@ -23,14 +23,13 @@ type wsConnector struct {
 	peer.CoreRunningTag
 	peer.CoreProcBundle
 	peer.CoreCaptureIOPanic
+	peer.CoreReconnector
 
 	defaultSes *wsSession
 
 	tryConnTimes int // 尝试连接次数
 
 	sesEndSignal sync.WaitGroup
-
-	reconDur time.Duration
 }
 
 func (self *wsConnector) Start() cellnet.Peer {
@@ -41,7 +40,7 @@ func (self *wsConnector) Start() cellnet.Peer {
 		return self
 	}
 
-	go self.connect(self.Address())
+	go self.connect()
 
 	return self
 }
@@ -74,6 +73,9 @@ func (self *wsConnector) Stop() {
 
 	self.StartStopping()
 
+	// 打断重连等待
+	self.InterruptReconnect()
+
 	// 通知发送关闭
 	self.defaultSes.Close()
 
@@ -81,24 +83,19 @@ func (self *wsConnector) Stop() {
 	self.WaitStopFinished()
 }
 
-func (self *wsConnector) ReconnectDuration() time.Duration {
-
-	return self.reconDur
-}
-
-func (self *wsConnector) SetReconnectDuration(v time.Duration) {
-	self.reconDur = v
-}
-
 const reportConnectFailedLimitTimes = 3
 
-func (self *wsConnector) connect(address string) {
+func (self *wsConnector) connect() {
 
 	self.SetRunning(true)
 
+	self.ResetReconnect()
+
 	for {
 		self.tryConnTimes++
 
+		address := self.ReconnectAddress(self.Address())
+
 		dialer := websocket.Dialer{}
 		dialer.Proxy = http.ProxyFromEnvironment
 		dialer.HandshakeTimeout = 45 * time.Second
@@ -130,8 +127,8 @@ func (self *wsConnector) connect(address string) {
 				}
 			}
 
-			// 没重连就退出
-			if self.ReconnectDuration() == 0 || self.IsStopping() {
+			// 没重连或放弃重连就退出，有重连就等待
+			if self.IsStopping() || !self.WaitReconnect(self, self.defaultSes, self.Address(), true) {
 
 				self.ProcEvent(&cellnet.RecvMsgEvent{
 					Ses: self.defaultSes,
@@ -140,9 +137,6 @@ func (self *wsConnector) connect(address string) {
 				break
 			}
 
-			// 有重连就等待
-			time.Sleep(self.ReconnectDuration())
-
 			// 继续连接
 			continue
 
@@ -153,6 +147,7 @@ func (self *wsConnector) connect(address string) {
 		self.defaultSes.Start()
 
 		self.tryConnTimes = 0
+		self.ReconnectSucceeded()
 
 		self.ProcEvent(&cellnet.RecvMsgEvent{Ses: self.defaultSes, Msg: &cellnet.SessionConnected{}})
 
@@ -160,13 +155,10 @@ func (self *wsConnector) connect(address string) {
 
 		self.defaultSes.conn = nil
 
-		// 没重连就退出/主动退出
-		if self.IsStopping() || self.ReconnectDuration() == 0 {
+		// 没重连就退出/主动退出，有重连就等待
+		if self.IsStopping() || !self.WaitReconnect(self, self.defaultSes, self.Address(), false) {
 			break
 		}
-
-		// 有重连就等待
-		time.Sleep(self.ReconnectDuration())
 	}
 
 	self.SetRunning(false)
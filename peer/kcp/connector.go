@@ -3,7 +3,6 @@ package kcp
 import (
 	"net"
 	"sync"
-	"time"
 
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/log"
@@ -18,6 +17,7 @@ type udpConnector struct {
 	peer.CoreContextSet
 	peer.CoreRunningTag
 	peer.CoreProcBundle
+	peer.CoreReconnector
 
 	remoteAddr *net.UDPAddr
 
@@ -26,8 +26,6 @@ type udpConnector struct {
 	tryConnTimes int // 尝试连接次数
 
 	sesEndSignal sync.WaitGroup
-
-	reconDur time.Duration
 }
 
 func (self *udpConnector) Start() cellnet.Peer {
@@ -65,11 +63,16 @@ func (self *udpConnector) IsReady() bool {
 
 func (self *udpConnector) connect() {
 	self.SetRunning(true)
+
+	self.ResetReconnect()
+
 	for {
 		self.tryConnTimes++
 
-		// 尝试用Socket连接地址
-		sess, err := kcp.DialWithOptions(self.remoteAddr.String(), nil, 0, 0)
+		// 尝试用Socket连接地址，首次连接使用 Start 时解析的地址
+		address := self.ReconnectAddress(self.remoteAddr.String())
+
+		sess, err := kcp.DialWithOptions(address, nil, 0, 0)
 		if err != nil {
 
 			if self.tryConnTimes <= reportConnectFailedLimitTimes {
@@ -80,8 +83,8 @@ func (self *udpConnector) connect() {
 				}
 			}
 
-			// 没重连就退出
-			if self.ReconnectDuration() == 0 || self.IsStopping() {
+			// 没重连或放弃重连就退出，有重连就等待
+			if self.IsStopping() || !self.WaitReconnect(self, self.defaultSes, self.remoteAddr.String(), true) {
 
 				self.ProcEvent(&cellnet.RecvMsgEvent{
 					Ses: self.defaultSes,
@@ -90,9 +93,6 @@ func (self *udpConnector) connect() {
 				break
 			}
 
-			// 有重连就等待
-			time.Sleep(self.ReconnectDuration())
-
 			// 继续连接
 			continue
 		}
@@ -105,6 +105,7 @@ func (self *udpConnector) connect() {
 		self.defaultSes.Start()
 
 		self.tryConnTimes = 0
+		self.ReconnectSucceeded()
 
 		self.ProcEvent(&cellnet.RecvMsgEvent{Ses: self.defaultSes, Msg: &cellnet.SessionConnected{}})
 
@@ -112,14 +113,11 @@ func (self *udpConnector) connect() {
 
 		self.defaultSes.SetKcpSession(nil)
 
-		// 没重连就退出/主动退出
-		if self.IsStopping() || self.ReconnectDuration() == 0 {
+		// 没重连就退出/主动退出，有重连就等待
+		if self.IsStopping() || !self.WaitReconnect(self, self.defaultSes, self.remoteAddr.String(), false) {
 			break
 		}
 
-		// 有重连就等待
-		time.Sleep(self.ReconnectDuration())
-
 		// 继续连接
 		continue
 
@@ -142,6 +140,9 @@ func (self *udpConnector) Stop() {
 
 	self.StartStopping()
 
+	// 打断重连等待
+	self.InterruptReconnect()
+
 	if c := self.defaultSes.GetKcpSession(); c != nil {
 		c.Close()
 	}
@@ -150,15 +151,6 @@ func (self *udpConnector) Stop() {
 	self.WaitStopFinished()
 }
 
-func (self *udpConnector) ReconnectDuration() time.Duration {
-
-	return self.reconDur
-}
-
-func (self *udpConnector) SetReconnectDuration(v time.Duration) {
-	self.reconDur = v
-}
-
 func (self *udpConnector) Port() int {
 
 	conn := self.defaultSes.GetKcpSession()
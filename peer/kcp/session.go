@@ -142,9 +142,7 @@ func (self *KcpSession) Close() {
 	// 将会话从管理器移除
 	self.Peer().(peer.SessionManager).Remove(self)
 
-	if self.endNotify != nil {
-		self.endNotify()
-	}
+	// 结束通知由收发线程都退出后的 Start 协程发出，这里不能重复通知
 	self.kcpSession.Close()
 }
 
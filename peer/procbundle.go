@@ -2,6 +2,8 @@ package peer
 
 import (
 	"errors"
+	"sync"
+	"sync/atomic"
 
 	"github.com/bobwong89757/cellnet"
 )
@@ -16,10 +18,9 @@ type MessagePoster interface {
 	ProcEvent(ev cellnet.Event)
 }
 
-// CoreProcBundle 提供消息处理资源包的核心实现
-// 包含消息传输器、事件钩子和用户回调
-// 所有 Peer 实现都可以嵌入此结构体来获得消息处理功能
-type CoreProcBundle struct {
+// procComponents 消息处理组件的快照
+// 创建后不再修改，替换组件时创建新的快照
+type procComponents struct {
 	// transmit 消息传输器
 	// 负责从网络连接读取消息和向网络连接写入消息
 	transmit cellnet.MessageTransmitter
@@ -33,28 +34,98 @@ type CoreProcBundle struct {
 	callback cellnet.EventCallback
 }
 
+// CoreProcBundle 提供消息处理资源包的核心实现
+// 包含消息传输器、事件钩子和用户回调
+// 所有 Peer 实现都可以嵌入此结构体来获得消息处理功能
+// 组件可以在运行中原子替换：每次收发使用同一份组件快照，替换不影响正在处理的消息，之后的消息使用新组件
+type CoreProcBundle struct {
+	// components 当前的消息处理组件，整体原子替换
+	components atomic.Pointer[procComponents]
+
+	// guard 串行化组件的修改，读取不需要加锁
+	guard sync.Mutex
+}
+
 // GetBundle 获取核心协议包
 // 返回自身，用于类型转换
 func (self *CoreProcBundle) GetBundle() *CoreProcBundle {
 	return self
 }
 
+// load 获取当前的组件快照
+func (self *CoreProcBundle) load() *procComponents {
+	if c := self.components.Load(); c != nil {
+		return c
+	}
+
+	return &procComponents{}
+}
+
+// update 基于当前的组件创建新的快照并替换
+func (self *CoreProcBundle) update(modify func(c *procComponents)) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	c := *self.load()
+	modify(&c)
+	self.components.Store(&c)
+}
+
 // SetTransmitter 设置消息传输器
 // v: 消息传输器，负责从网络连接读取消息和向网络连接写入消息
 func (self *CoreProcBundle) SetTransmitter(v cellnet.MessageTransmitter) {
-	self.transmit = v
+	self.update(func(c *procComponents) {
+		c.transmit = v
+	})
 }
 
 // SetHooker 设置事件钩子
 // v: 事件钩子，用于在消息处理流程中插入自定义逻辑
 func (self *CoreProcBundle) SetHooker(v cellnet.EventHooker) {
-	self.hooker = v
+	self.update(func(c *procComponents) {
+		c.hooker = v
+	})
 }
 
 // SetCallback 设置事件回调函数
 // v: 事件处理回调函数，当消息到达或系统事件发生时调用
 func (self *CoreProcBundle) SetCallback(v cellnet.EventCallback) {
-	self.callback = v
+	self.update(func(c *procComponents) {
+		c.callback = v
+	})
+}
+
+// SetComponents 同时替换消息传输器、事件钩子和事件回调函数
+// 三者作为一个整体替换，处理中的消息不会看到新旧组件混用的状态
+// proc.BindProcessorHandler 通过此方法绑定处理器，可以在运行中重新绑定
+func (self *CoreProcBundle) SetComponents(transmit cellnet.MessageTransmitter, hooker cellnet.EventHooker, callback cellnet.EventCallback) {
+	self.update(func(c *procComponents) {
+		c.transmit, c.hooker, c.callback = transmit, hooker, callback
+	})
+}
+
+// UpdateHooker 基于当前的事件钩子替换新的钩子
+// modify: 根据当前的钩子（可能为 nil）返回新的钩子，在修改锁内调用，期间其他修改等待
+// 用于在运行中的 Peer 上追加调试钩子，如 proc.NewMultiHooker(debugHooker, old)
+func (self *CoreProcBundle) UpdateHooker(modify func(old cellnet.EventHooker) cellnet.EventHooker) {
+	self.update(func(c *procComponents) {
+		c.hooker = modify(c.hooker)
+	})
+}
+
+// Transmitter 获取当前的消息传输器
+func (self *CoreProcBundle) Transmitter() cellnet.MessageTransmitter {
+	return self.load().transmit
+}
+
+// Hooker 获取当前的事件钩子
+func (self *CoreProcBundle) Hooker() cellnet.EventHooker {
+	return self.load().hooker
+}
+
+// Callback 获取当前的事件回调函数
+func (self *CoreProcBundle) Callback() cellnet.EventCallback {
+	return self.load().callback
 }
 
 // notHandled 表示传输器未设置的错误
@@ -65,9 +136,9 @@ var notHandled = errors.New("Processor: Transimitter nil")
 // 返回接收到的消息对象和错误信息
 // 如果传输器未设置，返回错误
 func (self *CoreProcBundle) ReadMessage(ses cellnet.Session) (msg interface{}, err error) {
-	if self.transmit != nil {
+	if transmit := self.load().transmit; transmit != nil {
 		// 使用传输器读取消息
-		return self.transmit.OnRecvMessage(ses)
+		return transmit.OnRecvMessage(ses)
 	}
 
 	return nil, notHandled
@@ -78,14 +149,16 @@ func (self *CoreProcBundle) ReadMessage(ses cellnet.Session) (msg interface{}, e
 // 消息会先经过 Hooker 处理，然后由传输器发送
 // 如果 Hooker 返回 nil，则停止发送
 func (self *CoreProcBundle) SendMessage(ev cellnet.Event) {
+	c := self.load()
+
 	// 如果有钩子，先处理出站事件
-	if self.hooker != nil {
-		ev = self.hooker.OnOutboundEvent(ev)
+	if c.hooker != nil {
+		ev = c.hooker.OnOutboundEvent(ev)
 	}
 
 	// 如果传输器已设置且事件不为 nil，发送消息
-	if self.transmit != nil && ev != nil {
-		self.transmit.OnSendMessage(ev.Session(), ev.Message())
+	if c.transmit != nil && ev != nil {
+		c.transmit.OnSendMessage(ev.Session(), ev.Message())
 	}
 }
 
@@ -93,14 +166,32 @@ func (self *CoreProcBundle) SendMessage(ev cellnet.Event) {
 // ev: 要处理的事件
 // 事件会先经过 Hooker 处理，然后到达用户回调
 // 如果 Hooker 返回 nil，则停止处理
+// 如果 Hooker 返回 cellnet.EventBatch，按顺序将其中的事件分别交给用户回调
+// 已经处理完毕的事件（cellnet.IsEventConsumed）不再交给用户回调
 func (self *CoreProcBundle) ProcEvent(ev cellnet.Event) {
+	c := self.load()
+
 	// 如果有钩子，先处理入站事件
-	if self.hooker != nil {
-		ev = self.hooker.OnInboundEvent(ev)
+	if c.hooker != nil {
+		ev = c.hooker.OnInboundEvent(ev)
 	}
 
 	// 如果有回调函数且事件不为 nil，调用回调
-	if self.callback != nil && ev != nil {
-		self.callback(ev)
+	if c.callback == nil || ev == nil {
+		return
+	}
+
+	if batch, ok := ev.(cellnet.EventBatch); ok {
+		for _, subEv := range batch.Events() {
+			if !cellnet.IsEventConsumed(subEv) {
+				c.callback(subEv)
+			}
+		}
+
+		return
+	}
+
+	if !cellnet.IsEventConsumed(ev) {
+		c.callback(ev)
 	}
 }
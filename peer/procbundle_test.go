@@ -0,0 +1,86 @@
+package peer
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+// genHooker 将事件的消息替换为自己的代数
+type genHooker int
+
+func (self genHooker) OnInboundEvent(ev cellnet.Event) cellnet.Event {
+	return &cellnet.RecvMsgEvent{Ses: ev.Session(), Msg: int(self)}
+}
+
+func (self genHooker) OnOutboundEvent(ev cellnet.Event) cellnet.Event {
+	return ev
+}
+
+func TestProcBundleSwap(t *testing.T) {
+
+	var bundle CoreProcBundle
+
+	var mismatch, processed int64
+
+	newCallback := func(gen int) cellnet.EventCallback {
+		return func(ev cellnet.Event) {
+			atomic.AddInt64(&processed, 1)
+
+			// 钩子和回调来自同一次替换
+			if ev.Message().(int) != gen {
+				atomic.AddInt64(&mismatch, 1)
+			}
+		}
+	}
+
+	bundle.SetComponents(nil, genHooker(0), newCallback(0))
+
+	var wg sync.WaitGroup
+
+	// 替换和处理交替进行，次数固定
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for n := 0; n < 1000; n++ {
+				bundle.ProcEvent(&cellnet.RecvMsgEvent{})
+
+				if n%10 == 0 {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	for gen := 1; gen <= 1000; gen++ {
+		bundle.SetComponents(nil, genHooker(gen), newCallback(gen))
+
+		if gen%10 == 0 {
+			runtime.Gosched()
+		}
+	}
+
+	wg.Wait()
+
+	if mismatch != 0 || processed != 4000 {
+		t.Fatal("components mixed during swap", mismatch, processed)
+	}
+
+	// 基于当前钩子追加
+	bundle.UpdateHooker(func(old cellnet.EventHooker) cellnet.EventHooker {
+		if old != genHooker(1000) {
+			t.Error("update hooker got wrong old hooker", old)
+		}
+
+		return genHooker(2000)
+	})
+
+	if bundle.Hooker() != genHooker(2000) || bundle.Callback() == nil || bundle.Transmitter() != nil {
+		t.Error("bundle getter mismatch")
+	}
+}
@@ -14,14 +14,23 @@ type ctx struct {
 	value interface{}
 }
 
+// ctxListLimit 使用列表存储上下文数据的最大数量
+// 键数量较少时线性查找列表更快，超过此数量后改用映射表存储
+const ctxListLimit = 16
+
 // CoreContextSet 提供上下文数据存储和访问的核心实现
 // 用于绑定用户自定义数据，支持任意类型的键值对
+// 键数量较少时使用列表存储，超过 ctxListLimit 后自动改用映射表存储
 // 线程安全，支持并发访问
 type CoreContextSet struct {
 	// ctxes 存储上下文数据的列表
 	ctxes []ctx
 
-	// ctxesGuard 保护 ctxes 的读写锁
+	// ctxByKey 存储上下文数据的映射表
+	// 键数量超过 ctxListLimit 后使用，此时 ctxes 为空
+	ctxByKey map[interface{}]interface{}
+
+	// ctxesGuard 保护 ctxes 和 ctxByKey 的读写锁
 	// 用于并发安全地访问上下文数据
 	ctxesGuard sync.RWMutex
 }
@@ -39,6 +48,15 @@ func (self *CoreContextSet) FetchContext(key, valuePtr interface{}) bool {
 		return false
 	}
 
+	fetchContextValue(pv, valuePtr)
+
+	return true
+}
+
+// fetchContextValue 将上下文数据设置到值指针
+// pv: 上下文数据的值
+// valuePtr: 指向目标值的指针
+func fetchContextValue(pv, valuePtr interface{}) {
 	// 根据值指针的类型进行类型断言和设置
 	switch rawValue := valuePtr.(type) {
 	case *string:
@@ -76,8 +94,6 @@ func (self *CoreContextSet) FetchContext(key, valuePtr interface{}) bool {
 			v.Set(reflect.ValueOf(pv))
 		}
 	}
-
-	return true
 }
 
 // GetContext 获取上下文数据
@@ -88,6 +104,16 @@ func (self *CoreContextSet) GetContext(key interface{}) (interface{}, bool) {
 	self.ctxesGuard.RLock()
 	defer self.ctxesGuard.RUnlock()
 
+	return self.getContext(key)
+}
+
+// getContext 在持有锁时获取上下文数据
+func (self *CoreContextSet) getContext(key interface{}) (interface{}, bool) {
+	if self.ctxByKey != nil {
+		v, ok := self.ctxByKey[key]
+		return v, ok
+	}
+
 	// 遍历上下文列表查找匹配的键
 	for _, t := range self.ctxes {
 		if t.key == key {
@@ -106,6 +132,17 @@ func (self *CoreContextSet) SetContext(key, v interface{}) {
 	self.ctxesGuard.Lock()
 	defer self.ctxesGuard.Unlock()
 
+	self.setContext(key, v)
+}
+
+// setContext 在持有写锁时设置上下文数据
+// 列表长度超过 ctxListLimit 时，将数据迁移到映射表
+func (self *CoreContextSet) setContext(key, v interface{}) {
+	if self.ctxByKey != nil {
+		self.ctxByKey[key] = v
+		return
+	}
+
 	// 查找是否已存在相同键的上下文
 	for i, t := range self.ctxes {
 		if t.key == key {
@@ -117,4 +154,54 @@ func (self *CoreContextSet) SetContext(key, v interface{}) {
 
 	// 添加新的上下文数据
 	self.ctxes = append(self.ctxes, ctx{key, v})
+
+	// 键数量过多，改用映射表存储
+	if len(self.ctxes) > ctxListLimit {
+		self.ctxByKey = make(map[interface{}]interface{}, len(self.ctxes)*2)
+		for _, t := range self.ctxes {
+			self.ctxByKey[t.key] = t.value
+		}
+
+		self.ctxes = nil
+	}
+}
+
+// DeleteContext 删除上下文数据
+// key: 上下文数据的键
+// 如果键不存在，不做任何处理
+func (self *CoreContextSet) DeleteContext(key interface{}) {
+	self.ctxesGuard.Lock()
+	defer self.ctxesGuard.Unlock()
+
+	if self.ctxByKey != nil {
+		delete(self.ctxByKey, key)
+		return
+	}
+
+	for i, t := range self.ctxes {
+		if t.key == key {
+			// 保持其余上下文数据的顺序
+			self.ctxes = append(self.ctxes[:i], self.ctxes[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetOrInitContext 获取上下文数据，不存在时使用 init 创建并保存
+// key: 上下文数据的键
+// init: 创建上下文数据的函数，在写锁内调用，不能再访问此 ContextSet
+// 返回上下文数据的值，以及是否已经存在
+// 获取与创建在同一个锁内完成，并发调用时 init 只会被调用一次
+func (self *CoreContextSet) GetOrInitContext(key interface{}, init func() interface{}) (interface{}, bool) {
+	self.ctxesGuard.Lock()
+	defer self.ctxesGuard.Unlock()
+
+	if v, ok := self.getContext(key); ok {
+		return v, true
+	}
+
+	v := init()
+	self.setContext(key, v)
+
+	return v, false
 }
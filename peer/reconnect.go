@@ -0,0 +1,317 @@
+package peer
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+// FixedReconnectPolicy 固定间隔的重连策略
+// SetReconnectDuration 使用此策略
+type FixedReconnectPolicy struct {
+	// Interval 重连间隔
+	Interval time.Duration
+
+	// MaxAttempts 最大连续重连次数，0 表示不限制
+	MaxAttempts int
+}
+
+// NextDelay 返回固定的重连间隔
+func (self *FixedReconnectPolicy) NextDelay(attempt int) (time.Duration, bool) {
+	if self.MaxAttempts > 0 && attempt > self.MaxAttempts {
+		return 0, false
+	}
+
+	return self.Interval, true
+}
+
+// BackoffReconnectPolicy 指数退避的重连策略
+// 第 n 次重连的基础间隔为 Min * Factor^(n-1)，不超过 Max
+// 设置 Jitter 后在基础间隔上随机缩短，避免大量连接器同时重连
+type BackoffReconnectPolicy struct {
+	// Min 第一次重连的间隔
+	Min time.Duration
+
+	// Max 重连间隔的上限，0 表示不限制
+	Max time.Duration
+
+	// Factor 每次重连间隔的增长倍数，小于等于 1 时使用 2
+	Factor float64
+
+	// Jitter 随机抖动比例，取值 0~1
+	// 实际间隔在 [基础间隔*(1-Jitter), 基础间隔] 之间随机
+	Jitter float64
+
+	// MaxAttempts 最大连续重连次数，0 表示不限制
+	MaxAttempts int
+}
+
+// NextDelay 返回第 attempt 次重连的退避间隔
+func (self *BackoffReconnectPolicy) NextDelay(attempt int) (time.Duration, bool) {
+	if self.MaxAttempts > 0 && attempt > self.MaxAttempts {
+		return 0, false
+	}
+
+	factor := self.Factor
+	if factor <= 1 {
+		factor = 2
+	}
+
+	delay := float64(self.Min)
+	for i := 1; i < attempt; i++ {
+		delay *= factor
+
+		if self.Max > 0 && delay >= float64(self.Max) {
+			break
+		}
+	}
+
+	if self.Max > 0 && delay > float64(self.Max) {
+		delay = float64(self.Max)
+	}
+
+	if self.Jitter > 0 {
+		jitter := self.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+
+		delay -= delay * jitter * rand.Float64()
+	}
+
+	return time.Duration(delay), true
+}
+
+// CoreReconnector 连接器重连功能的核心实现
+// 管理重连策略、备用地址和重连计数，实现 cellnet.Reconnector 接口
+// 连接器嵌入此结构体，在连接循环中调用 ReconnectAddress 和 WaitReconnect
+type CoreReconnector struct {
+	// guard 保护以下字段
+	guard sync.Mutex
+
+	// policy 重连策略，nil 表示不自动重连
+	policy cellnet.ReconnectPolicy
+
+	// reconDur SetReconnectDuration 设置的重连间隔
+	reconDur time.Duration
+
+	// failoverMode 地址切换方式
+	failoverMode cellnet.FailoverMode
+
+	// failoverAddrs 备用地址列表
+	failoverAddrs []string
+
+	// addrIndex 当前使用的地址索引，0 为 Peer 的 Address
+	addrIndex int
+
+	// attempt 连续重连次数，连接成功后清零
+	attempt int
+
+	// interrupt 用于在停止时打断重连等待
+	interrupt chan struct{}
+}
+
+// SetReconnectDuration 设置固定的重连间隔
+// v: 重连间隔，0 表示关闭自动重连
+func (self *CoreReconnector) SetReconnectDuration(v time.Duration) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.reconDur = v
+
+	if v > 0 {
+		self.policy = &FixedReconnectPolicy{Interval: v}
+	} else {
+		self.policy = nil
+	}
+}
+
+// ReconnectDuration 获取 SetReconnectDuration 设置的重连间隔
+// 使用 SetReconnectPolicy 设置其他策略后返回 0
+func (self *CoreReconnector) ReconnectDuration() time.Duration {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return self.reconDur
+}
+
+// SetReconnectPolicy 设置重连策略
+// policy: 重连策略，nil 表示不自动重连
+func (self *CoreReconnector) SetReconnectPolicy(policy cellnet.ReconnectPolicy) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.policy = policy
+
+	if fixed, ok := policy.(*FixedReconnectPolicy); ok && fixed.MaxAttempts == 0 {
+		self.reconDur = fixed.Interval
+	} else {
+		self.reconDur = 0
+	}
+}
+
+// ReconnectPolicy 获取当前的重连策略
+func (self *CoreReconnector) ReconnectPolicy() cellnet.ReconnectPolicy {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return self.policy
+}
+
+// SetFailoverAddress 设置备用连接地址
+// mode: 地址切换方式
+// addrs: 备用地址列表，排在 Peer 的 Address 之后
+func (self *CoreReconnector) SetFailoverAddress(mode cellnet.FailoverMode, addrs ...string) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.failoverMode = mode
+	self.failoverAddrs = append([]string(nil), addrs...)
+	self.addrIndex = 0
+}
+
+// ResetReconnect 重置重连状态
+// 连接器开始连接时调用
+func (self *CoreReconnector) ResetReconnect() {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.attempt = 0
+	self.addrIndex = 0
+
+	if self.interrupt == nil {
+		self.interrupt = make(chan struct{}, 1)
+	}
+
+	// 清除上一次停止遗留的打断信号
+	select {
+	case <-self.interrupt:
+	default:
+	}
+}
+
+// InterruptReconnect 打断正在进行的重连等待
+// 连接器停止时调用
+func (self *CoreReconnector) InterruptReconnect() {
+	self.guard.Lock()
+	interrupt := self.interrupt
+	self.guard.Unlock()
+
+	if interrupt == nil {
+		return
+	}
+
+	select {
+	case interrupt <- struct{}{}:
+	default:
+	}
+}
+
+// ReconnectAddress 返回本次连接使用的地址
+// primary: Peer 的 Address
+func (self *CoreReconnector) ReconnectAddress(primary string) string {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if self.addrIndex == 0 || self.addrIndex > len(self.failoverAddrs) {
+		return primary
+	}
+
+	return self.failoverAddrs[self.addrIndex-1]
+}
+
+// ReconnectSucceeded 连接成功时调用，清零重连次数
+func (self *CoreReconnector) ReconnectSucceeded() {
+	self.guard.Lock()
+	self.attempt = 0
+	self.guard.Unlock()
+}
+
+// WaitReconnect 按重连策略等待下一次重连
+// poster: 用于投递重连事件，通常为连接器自身
+// ses: 重连事件使用的会话
+// primary: Peer 的 Address
+// failed: 上一次是否为连接失败，false 表示连接成功后断开
+// 等待前投递 SessionReconnecting 事件，策略放弃时投递 SessionReconnectGiveUp 事件
+// 返回 false 表示不再重连：没有设置重连、策略放弃或被 InterruptReconnect 打断
+func (self *CoreReconnector) WaitReconnect(poster MessagePoster, ses cellnet.Session, primary string, failed bool) bool {
+	self.guard.Lock()
+
+	if self.policy == nil {
+		self.guard.Unlock()
+		return false
+	}
+
+	self.attempt++
+	attempt := self.attempt
+
+	delay, ok := self.policy.NextDelay(attempt)
+	if !ok {
+		self.guard.Unlock()
+
+		poster.ProcEvent(&cellnet.RecvMsgEvent{
+			Ses: ses,
+			Msg: &cellnet.SessionReconnectGiveUp{Attempts: int32(attempt - 1)},
+		})
+		return false
+	}
+
+	self.nextAddress(failed)
+
+	address := primary
+	if self.addrIndex > 0 {
+		address = self.failoverAddrs[self.addrIndex-1]
+	}
+
+	interrupt := self.interrupt
+
+	self.guard.Unlock()
+
+	poster.ProcEvent(&cellnet.RecvMsgEvent{
+		Ses: ses,
+		Msg: &cellnet.SessionReconnecting{
+			Attempt: int32(attempt),
+			Address: address,
+			Delay:   delay,
+		},
+	})
+
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-interrupt:
+		return false
+	}
+}
+
+// nextAddress 按切换方式选择下一次连接的地址
+// 必须在持有锁时调用
+func (self *CoreReconnector) nextAddress(failed bool) {
+	total := len(self.failoverAddrs) + 1
+
+	if total == 1 {
+		self.addrIndex = 0
+		return
+	}
+
+	switch self.failoverMode {
+	case cellnet.FailoverRoundRobin:
+		self.addrIndex = (self.addrIndex + 1) % total
+	default:
+		// 按优先级：断开后从第一个地址重新开始，连接失败时尝试下一个地址
+		if failed {
+			self.addrIndex = (self.addrIndex + 1) % total
+		} else {
+			self.addrIndex = 0
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package peer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+type reconnectTestPoster struct {
+	events []interface{}
+}
+
+func (self *reconnectTestPoster) ProcEvent(ev cellnet.Event) {
+	self.events = append(self.events, ev.Message())
+}
+
+func TestBackoffReconnectPolicy(t *testing.T) {
+
+	policy := &BackoffReconnectPolicy{
+		Min:         time.Millisecond * 100,
+		Max:         time.Second,
+		MaxAttempts: 6,
+	}
+
+	expect := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, ms := range expect {
+		delay, ok := policy.NextDelay(i + 1)
+		if !ok || delay != ms*time.Millisecond {
+			t.Fatalf("attempt %d: expect %v, got %v %v", i+1, ms*time.Millisecond, delay, ok)
+		}
+	}
+
+	if _, ok := policy.NextDelay(7); ok {
+		t.Fatal("expect give up after max attempts")
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay, _ := policy.NextDelay(3)
+		if delay < time.Millisecond*200 || delay > time.Millisecond*400 {
+			t.Fatal("jitter out of range", delay)
+		}
+	}
+}
+
+func TestCoreReconnectorFailover(t *testing.T) {
+
+	var (
+		r      CoreReconnector
+		poster reconnectTestPoster
+	)
+
+	if r.WaitReconnect(&poster, nil, "a", true) {
+		t.Fatal("expect no reconnect without policy")
+	}
+
+	r.SetReconnectPolicy(&FixedReconnectPolicy{MaxAttempts: 4})
+	r.SetFailoverAddress(cellnet.FailoverPriority, "b", "c")
+	r.ResetReconnect()
+
+	var addrs []string
+	for i := 0; i < 4; i++ {
+		if !r.WaitReconnect(&poster, nil, "a", true) {
+			t.Fatal("unexpected give up")
+		}
+		addrs = append(addrs, r.ReconnectAddress("a"))
+	}
+
+	if addrs[0] != "b" || addrs[1] != "c" || addrs[2] != "a" || addrs[3] != "b" {
+		t.Fatal("priority failover order mismatch", addrs)
+	}
+
+	if r.WaitReconnect(&poster, nil, "a", true) {
+		t.Fatal("expect give up")
+	}
+
+	if giveUp, ok := poster.events[len(poster.events)-1].(*cellnet.SessionReconnectGiveUp); !ok || giveUp.Attempts != 4 {
+		t.Fatal("expect give up event", poster.events)
+	}
+
+	// 连接成功后断开，按优先级回到第一个地址
+	r.ReconnectSucceeded()
+	r.WaitReconnect(&poster, nil, "a", false)
+	if addr := r.ReconnectAddress("a"); addr != "a" {
+		t.Fatal("expect primary address after disconnect", addr)
+	}
+
+	// 轮询时断开后也切换到下一个地址
+	r.SetFailoverAddress(cellnet.FailoverRoundRobin, "b")
+	r.ResetReconnect()
+	r.WaitReconnect(&poster, nil, "a", false)
+	if addr := r.ReconnectAddress("a"); addr != "b" {
+		t.Fatal("expect next address in round robin", addr)
+	}
+}
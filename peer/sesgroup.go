@@ -0,0 +1,248 @@
+package peer
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+// SessionGroupManager 定义会话分组管理接口
+// 用于实现房间、频道、公会等按组管理会话的功能
+// 会话可以同时加入多个分组，会话结束时自动退出所有分组
+type SessionGroupManager interface {
+	// JoinGroup 将会话加入分组
+	// 分组不存在时自动创建
+	// 返回 true 表示新加入，false 表示会话已在分组中
+	JoinGroup(group string, ses cellnet.Session) bool
+
+	// LeaveGroup 将会话移出分组
+	// 分组为空时自动删除
+	// 返回 true 表示成功移出，false 表示会话不在分组中
+	LeaveGroup(group string, ses cellnet.Session) bool
+
+	// LeaveAllGroups 将会话移出所有已加入的分组
+	LeaveAllGroups(ses cellnet.Session)
+
+	// IsInGroup 检查会话是否在分组中
+	IsInGroup(group string, ses cellnet.Session) bool
+
+	// SessionGroups 获取会话已加入的所有分组名称，按字母顺序排序
+	SessionGroups(ses cellnet.Session) []string
+
+	// VisitGroupSession 遍历分组中的所有会话
+	// 如果回调返回 false，则停止遍历
+	VisitGroupSession(group string, callback func(cellnet.Session) bool)
+
+	// GroupSessionCount 返回分组中的会话数量
+	GroupSessionCount(group string) int
+
+	// GroupList 返回所有非空分组的名称，按字母顺序排序
+	GroupList() []string
+
+	// BroadcastGroup 向分组中的所有会话发送消息
+	BroadcastGroup(group string, msg interface{})
+}
+
+// CoreSessionGroupManager 提供会话分组管理的核心实现
+// 零值可直接使用，所有方法都可以在任意 goroutine 中并发调用
+// CoreSessionManager 内嵌此结构体，会话从管理器移除（连接结束）时自动退出所有分组
+// 也可以通过 NewSessionGroupManager 单独创建，用于跨 Peer 的分组
+type CoreSessionGroupManager struct {
+	// sesByGroup 分组到会话集合的映射
+	sesByGroup map[string]map[cellnet.Session]struct{}
+
+	// groupBySes 会话到已加入分组集合的映射
+	// 用于会话结束时快速退出所有分组
+	groupBySes map[cellnet.Session]map[string]struct{}
+
+	// groupGuard 保护分组映射的读写锁
+	groupGuard sync.RWMutex
+}
+
+// JoinGroup 将会话加入分组
+// group: 分组名称
+// ses: 要加入的会话，为 nil 时忽略
+// 返回 true 表示新加入，false 表示会话已在分组中
+func (self *CoreSessionGroupManager) JoinGroup(group string, ses cellnet.Session) bool {
+	if ses == nil {
+		return false
+	}
+
+	self.groupGuard.Lock()
+	defer self.groupGuard.Unlock()
+
+	// 延迟初始化，保证零值可用
+	if self.sesByGroup == nil {
+		self.sesByGroup = make(map[string]map[cellnet.Session]struct{})
+		self.groupBySes = make(map[cellnet.Session]map[string]struct{})
+	}
+
+	members, ok := self.sesByGroup[group]
+	if !ok {
+		members = make(map[cellnet.Session]struct{})
+		self.sesByGroup[group] = members
+	}
+
+	if _, ok := members[ses]; ok {
+		return false
+	}
+
+	members[ses] = struct{}{}
+
+	groups, ok := self.groupBySes[ses]
+	if !ok {
+		groups = make(map[string]struct{})
+		self.groupBySes[ses] = groups
+	}
+
+	groups[group] = struct{}{}
+
+	return true
+}
+
+// LeaveGroup 将会话移出分组
+// group: 分组名称
+// ses: 要移出的会话
+// 返回 true 表示成功移出，false 表示会话不在分组中
+func (self *CoreSessionGroupManager) LeaveGroup(group string, ses cellnet.Session) bool {
+	self.groupGuard.Lock()
+	defer self.groupGuard.Unlock()
+
+	return self.leaveGroup(group, ses)
+}
+
+// leaveGroup 在持有写锁时将会话移出分组
+// 分组或会话的集合为空时，删除对应的映射项
+func (self *CoreSessionGroupManager) leaveGroup(group string, ses cellnet.Session) bool {
+	members, ok := self.sesByGroup[group]
+	if !ok {
+		return false
+	}
+
+	if _, ok := members[ses]; !ok {
+		return false
+	}
+
+	delete(members, ses)
+	if len(members) == 0 {
+		delete(self.sesByGroup, group)
+	}
+
+	if groups, ok := self.groupBySes[ses]; ok {
+		delete(groups, group)
+		if len(groups) == 0 {
+			delete(self.groupBySes, ses)
+		}
+	}
+
+	return true
+}
+
+// LeaveAllGroups 将会话移出所有已加入的分组
+// ses: 要移出的会话
+// 会话结束时由 CoreSessionManager.Remove 自动调用
+func (self *CoreSessionGroupManager) LeaveAllGroups(ses cellnet.Session) {
+	self.groupGuard.Lock()
+	defer self.groupGuard.Unlock()
+
+	for group := range self.groupBySes[ses] {
+		self.leaveGroup(group, ses)
+	}
+}
+
+// IsInGroup 检查会话是否在分组中
+// group: 分组名称
+// ses: 要检查的会话
+func (self *CoreSessionGroupManager) IsInGroup(group string, ses cellnet.Session) bool {
+	self.groupGuard.RLock()
+	defer self.groupGuard.RUnlock()
+
+	_, ok := self.sesByGroup[group][ses]
+	return ok
+}
+
+// SessionGroups 获取会话已加入的所有分组名称
+// ses: 要查询的会话
+// 返回按字母顺序排序的分组名称列表，未加入任何分组时返回 nil
+func (self *CoreSessionGroupManager) SessionGroups(ses cellnet.Session) (ret []string) {
+	self.groupGuard.RLock()
+	for group := range self.groupBySes[ses] {
+		ret = append(ret, group)
+	}
+	self.groupGuard.RUnlock()
+
+	sort.Strings(ret)
+	return
+}
+
+// VisitGroupSession 遍历分组中的所有会话
+// group: 分组名称
+// callback: 遍历回调函数，如果返回 false，则停止遍历
+// 遍历的是调用时刻的成员快照，回调中可以安全地加入或退出分组
+func (self *CoreSessionGroupManager) VisitGroupSession(group string, callback func(cellnet.Session) bool) {
+	for _, ses := range self.groupSnapshot(group) {
+		if !callback(ses) {
+			break
+		}
+	}
+}
+
+// groupSnapshot 复制分组当前的成员列表
+// 避免在持有锁时调用用户回调或发送消息
+func (self *CoreSessionGroupManager) groupSnapshot(group string) []cellnet.Session {
+	self.groupGuard.RLock()
+	defer self.groupGuard.RUnlock()
+
+	members := self.sesByGroup[group]
+	if len(members) == 0 {
+		return nil
+	}
+
+	list := make([]cellnet.Session, 0, len(members))
+	for ses := range members {
+		list = append(list, ses)
+	}
+
+	return list
+}
+
+// GroupSessionCount 返回分组中的会话数量
+// group: 分组名称
+// 分组不存在时返回 0
+func (self *CoreSessionGroupManager) GroupSessionCount(group string) int {
+	self.groupGuard.RLock()
+	defer self.groupGuard.RUnlock()
+
+	return len(self.sesByGroup[group])
+}
+
+// GroupList 返回所有非空分组的名称
+// 返回按字母顺序排序的分组名称列表
+func (self *CoreSessionGroupManager) GroupList() (ret []string) {
+	self.groupGuard.RLock()
+	for group := range self.sesByGroup {
+		ret = append(ret, group)
+	}
+	self.groupGuard.RUnlock()
+
+	sort.Strings(ret)
+	return
+}
+
+// BroadcastGroup 向分组中的所有会话发送消息
+// group: 分组名称
+// msg: 要发送的消息对象
+// 消息发送是异步的，不会阻塞调用者
+func (self *CoreSessionGroupManager) BroadcastGroup(group string, msg interface{}) {
+	for _, ses := range self.groupSnapshot(group) {
+		ses.Send(msg)
+	}
+}
+
+// NewSessionGroupManager 创建独立的会话分组管理器
+// 适用于成员来自多个 Peer 的分组
+// 需要在处理 cellnet.SessionClosed 事件时调用 LeaveAllGroups 清理会话
+func NewSessionGroupManager() *CoreSessionGroupManager {
+	return &CoreSessionGroupManager{}
+}
@@ -0,0 +1,67 @@
+package peer
+
+import (
+	"testing"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+type groupTestSession struct {
+	CoreSessionIdentify
+	sent []interface{}
+}
+
+func (self *groupTestSession) Raw() interface{}     { return nil }
+func (self *groupTestSession) Peer() cellnet.Peer   { return nil }
+func (self *groupTestSession) Send(msg interface{}) { self.sent = append(self.sent, msg) }
+func (self *groupTestSession) Close()               {}
+
+func TestSessionGroup(t *testing.T) {
+
+	var mgr CoreSessionManager
+
+	a := new(groupTestSession)
+	b := new(groupTestSession)
+	mgr.Add(a)
+	mgr.Add(b)
+
+	if !mgr.JoinGroup("room1", a) || !mgr.JoinGroup("room1", b) || !mgr.JoinGroup("guild", a) {
+		t.Fatal("join failed")
+	}
+
+	if mgr.JoinGroup("room1", a) {
+		t.Fatal("duplicate join should return false")
+	}
+
+	if mgr.GroupSessionCount("room1") != 2 {
+		t.Fatal("room1 count mismatch")
+	}
+
+	if groups := mgr.SessionGroups(a); len(groups) != 2 || groups[0] != "guild" || groups[1] != "room1" {
+		t.Fatal("session groups mismatch", groups)
+	}
+
+	mgr.BroadcastGroup("room1", "hello")
+	if len(a.sent) != 1 || len(b.sent) != 1 {
+		t.Fatal("broadcast not delivered")
+	}
+
+	// 会话结束后自动退出所有分组
+	mgr.Remove(a)
+
+	if mgr.IsInGroup("room1", a) || mgr.GroupSessionCount("guild") != 0 {
+		t.Fatal("removed session still in group")
+	}
+
+	if list := mgr.GroupList(); len(list) != 1 || list[0] != "room1" {
+		t.Fatal("group list mismatch", list)
+	}
+
+	if !mgr.LeaveGroup("room1", b) || mgr.LeaveGroup("room1", b) {
+		t.Fatal("leave failed")
+	}
+
+	if len(mgr.GroupList()) != 0 {
+		t.Fatal("empty group not removed")
+	}
+}
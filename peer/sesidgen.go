@@ -0,0 +1,141 @@
+package peer
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SessionIDGenerator 定义会话 ID 生成器接口
+// CoreSessionManager 在添加会话时调用此接口为会话分配 ID
+// 实现必须保证并发安全，且生成的 ID 大于 0
+type SessionIDGenerator interface {
+	// NextID 生成一个新的会话 ID
+	NextID() int64
+}
+
+// CounterIDGenerator 基于原子递增计数器的会话 ID 生成器
+// CoreSessionManager 的默认生成器，ID 只在单个进程的单个 Peer 内唯一
+type CounterIDGenerator struct {
+	// seq 已经生成的会话 ID 流水号
+	// 使用原子操作保证并发安全
+	seq int64
+}
+
+// NextID 生成一个新的会话 ID
+// 返回递增后的流水号
+func (self *CounterIDGenerator) NextID() int64 {
+	return atomic.AddInt64(&self.seq, 1)
+}
+
+// SetBase 设置会话 ID 的起始值
+// base: 后续会话 ID 会从 base+1 开始递增
+func (self *CounterIDGenerator) SetBase(base int64) {
+	atomic.StoreInt64(&self.seq, base)
+}
+
+// 雪花算法的位分配
+// 从高到低依次为：1 位符号位（始终为 0）、41 位毫秒时间戳、10 位节点 ID、12 位序列号
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+
+	// SnowflakeMaxNodeID 雪花算法允许的最大节点 ID
+	SnowflakeMaxNodeID = 1<<snowflakeNodeBits - 1
+
+	snowflakeSeqMask   = 1<<snowflakeSeqBits - 1
+	snowflakeNodeShift = snowflakeSeqBits
+	snowflakeTimeShift = snowflakeSeqBits + snowflakeNodeBits
+)
+
+// SnowflakeEpoch 雪花算法时间戳的起始时间（2020-01-01 00:00:00 UTC）
+// 41 位毫秒时间戳可以使用约 69 年
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrInvalidNodeID 表示雪花算法节点 ID 超出范围的错误
+var ErrInvalidNodeID = errors.New("peer: snowflake node id out of range")
+
+// SnowflakeIDGenerator 雪花算法会话 ID 生成器
+// ID 由时间戳、节点 ID 和序列号组成，多个进程使用不同的节点 ID 时生成的 ID 全局唯一
+// 适用于网关等需要将会话 ID 透传到后端服务的场景
+// 同一毫秒内最多生成 4096 个 ID，超出时等待下一毫秒
+type SnowflakeIDGenerator struct {
+	// nodeID 节点 ID，取值范围 [0, SnowflakeMaxNodeID]
+	nodeID int64
+
+	// lastTime 上次生成 ID 时的毫秒时间戳（相对于 SnowflakeEpoch）
+	lastTime int64
+
+	// seq 同一毫秒内的序列号
+	seq int64
+
+	// guard 保护生成状态的互斥锁
+	guard sync.Mutex
+}
+
+// NextID 生成一个新的会话 ID
+// 系统时钟回拨时，沿用上次的时间戳继续递增序列号，保证 ID 不重复
+func (self *SnowflakeIDGenerator) NextID() int64 {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	ts := self.elapsed()
+
+	// 时钟回拨时沿用上次的时间戳
+	if ts < self.lastTime {
+		ts = self.lastTime
+	}
+
+	if ts == self.lastTime {
+		self.seq = (self.seq + 1) & snowflakeSeqMask
+
+		// 本毫秒的序列号用完，等待下一毫秒
+		if self.seq == 0 {
+			for ts <= self.lastTime {
+				time.Sleep(time.Millisecond / 10)
+				ts = self.elapsed()
+			}
+		}
+	} else {
+		self.seq = 0
+	}
+
+	self.lastTime = ts
+
+	return ts<<snowflakeTimeShift | self.nodeID<<snowflakeNodeShift | self.seq
+}
+
+// elapsed 返回从 SnowflakeEpoch 到当前时间的毫秒数
+func (self *SnowflakeIDGenerator) elapsed() int64 {
+	return time.Since(SnowflakeEpoch).Nanoseconds() / int64(time.Millisecond)
+}
+
+// NodeID 返回生成器的节点 ID
+func (self *SnowflakeIDGenerator) NodeID() int64 {
+	return self.nodeID
+}
+
+// NewSnowflakeIDGenerator 创建雪花算法会话 ID 生成器
+// nodeID: 节点 ID，每个进程必须使用不同的值，取值范围 [0, SnowflakeMaxNodeID]
+// 节点 ID 超出范围时返回 ErrInvalidNodeID
+func NewSnowflakeIDGenerator(nodeID int64) (*SnowflakeIDGenerator, error) {
+	if nodeID < 0 || nodeID > SnowflakeMaxNodeID {
+		return nil, ErrInvalidNodeID
+	}
+
+	return &SnowflakeIDGenerator{
+		nodeID: nodeID,
+	}, nil
+}
+
+// SnowflakeNodeIDOf 从雪花算法生成的 ID 中解析出节点 ID
+// 后端服务可以据此判断会话来自哪个网关进程
+func SnowflakeNodeIDOf(id int64) int64 {
+	return (id >> snowflakeNodeShift) & SnowflakeMaxNodeID
+}
+
+// SnowflakeTimeOf 从雪花算法生成的 ID 中解析出生成时间
+func SnowflakeTimeOf(id int64) time.Time {
+	return SnowflakeEpoch.Add(time.Duration(id>>snowflakeTimeShift) * time.Millisecond)
+}
@@ -0,0 +1,68 @@
+package peer
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSnowflakeIDGenerator(t *testing.T) {
+
+	if _, err := NewSnowflakeIDGenerator(SnowflakeMaxNodeID + 1); err != ErrInvalidNodeID {
+		t.Fatal("expect node id range error")
+	}
+
+	gen, err := NewSnowflakeIDGenerator(37)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		guard sync.Mutex
+		idSet = make(map[int64]bool)
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5000; j++ {
+				id := gen.NextID()
+
+				guard.Lock()
+				if idSet[id] || id <= 0 {
+					t.Error("invalid or duplicate id", id)
+				}
+				idSet[id] = true
+				guard.Unlock()
+
+				if SnowflakeNodeIDOf(id) != 37 {
+					t.Error("node id mismatch", id)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestSessionIDGenerator(t *testing.T) {
+
+	var mgr CoreSessionManager
+	mgr.SetIDBase(100)
+
+	ses := new(groupTestSession)
+	mgr.Add(ses)
+	if ses.ID() != 101 {
+		t.Fatal("counter id mismatch", ses.ID())
+	}
+
+	gen, _ := NewSnowflakeIDGenerator(5)
+	mgr.SetIDGenerator(gen)
+
+	ses = new(groupTestSession)
+	mgr.Add(ses)
+	if SnowflakeNodeIDOf(ses.ID()) != 5 || mgr.GetSession(ses.ID()) != ses {
+		t.Fatal("snowflake id mismatch", ses.ID())
+	}
+}
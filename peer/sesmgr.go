@@ -13,11 +13,20 @@ import (
 type SessionManager interface {
 	cellnet.SessionAccessor
 
+	// SessionGroupManager 会话分组管理
+	// 会话被移除时自动退出所有分组
+	SessionGroupManager
+
 	// Add 添加一个会话到管理器
 	// 会话会被分配一个唯一的 ID
 	Add(cellnet.Session)
 
+	// Store 使用会话已有的 ID 添加会话，不分配新的 ID
+	// 已经存在相同 ID 的会话时替换，用于使用逻辑会话替代物理会话
+	Store(cellnet.Session)
+
 	// Remove 从管理器中移除一个会话
+	// 相同 ID 下存储的是其他会话时不移除
 	Remove(cellnet.Session)
 
 	// Count 返回当前会话数量
@@ -25,20 +34,31 @@ type SessionManager interface {
 
 	// SetIDBase 设置会话 ID 的起始值
 	// base: 会话 ID 的起始值，后续会话 ID 会从此值开始递增
+	// 仅对默认的计数器生成器有效
 	SetIDBase(base int64)
+
+	// SetIDGenerator 设置会话 ID 生成器
+	// gen: 会话 ID 生成器，为 nil 时恢复为默认的计数器生成器
+	SetIDGenerator(gen SessionIDGenerator)
 }
 
 // CoreSessionManager 提供会话管理的核心实现
 // 使用 sync.Map 存储会话，支持并发访问
 // 自动为每个会话分配唯一的 ID
+// 内嵌 CoreSessionGroupManager 提供会话分组功能
 type CoreSessionManager struct {
+	CoreSessionGroupManager
+
 	// sesById 使用会话 ID 关联会话的映射表
 	// 键为会话 ID（int64），值为 Session
 	sesById sync.Map
 
-	// sesIDGen 记录已经生成的会话 ID 流水号
-	// 使用原子操作保证并发安全
-	sesIDGen int64
+	// sesIDGen 默认的会话 ID 生成器，记录已经生成的会话 ID 流水号
+	sesIDGen CounterIDGenerator
+
+	// idGen 自定义的会话 ID 生成器
+	// 为 nil 时使用 sesIDGen
+	idGen SessionIDGenerator
 
 	// count 记录当前在使用的会话数量
 	// 使用原子操作保证并发安全
@@ -48,8 +68,16 @@ type CoreSessionManager struct {
 // SetIDBase 设置会话 ID 的起始值
 // base: 会话 ID 的起始值
 // 后续添加的会话 ID 会从此值开始递增
+// 仅对默认的计数器生成器有效
 func (self *CoreSessionManager) SetIDBase(base int64) {
-	atomic.StoreInt64(&self.sesIDGen, base)
+	self.sesIDGen.SetBase(base)
+}
+
+// SetIDGenerator 设置会话 ID 生成器
+// gen: 会话 ID 生成器，为 nil 时恢复为默认的计数器生成器
+// 需要在 Peer 启动前设置
+func (self *CoreSessionManager) SetIDGenerator(gen SessionIDGenerator) {
+	self.idGen = gen
 }
 
 // Count 获取当前会话数量
@@ -62,8 +90,13 @@ func (self *CoreSessionManager) Count() int {
 // ses: 要添加的会话
 // 会话会被分配一个唯一的 ID，并存储到管理器中
 func (self *CoreSessionManager) Add(ses cellnet.Session) {
-	// 生成新的会话 ID（原子递增）
-	id := atomic.AddInt64(&self.sesIDGen, 1)
+	// 生成新的会话 ID
+	var id int64
+	if self.idGen != nil {
+		id = self.idGen.NextID()
+	} else {
+		id = self.sesIDGen.NextID()
+	}
 
 	// 增加会话计数
 	atomic.AddInt64(&self.count, 1)
@@ -77,15 +110,27 @@ func (self *CoreSessionManager) Add(ses cellnet.Session) {
 	self.sesById.Store(id, ses)
 }
 
+// Store 使用会话已有的 ID 添加会话
+// ses: 要添加的会话
+// 不分配新的 ID，已经存在相同 ID 的会话时替换，会话计数不变
+func (self *CoreSessionManager) Store(ses cellnet.Session) {
+	if _, loaded := self.sesById.Swap(ses.ID(), ses); !loaded {
+		atomic.AddInt64(&self.count, 1)
+	}
+}
+
 // Remove 从管理器中移除一个会话
 // ses: 要移除的会话
-// 会话会从映射表中删除，并减少会话计数
+// 会话会从映射表中删除，退出所有分组，并减少会话计数
+// 相同 ID 下存储的是其他会话时（如已经被 Store 替换），只退出分组
 func (self *CoreSessionManager) Remove(ses cellnet.Session) {
-	// 从映射表中删除会话
-	self.sesById.Delete(ses.ID())
+	// 会话结束，退出所有分组
+	self.LeaveAllGroups(ses)
 
-	// 减少会话计数
-	atomic.AddInt64(&self.count, -1)
+	// 从映射表中删除会话，并减少会话计数
+	if self.sesById.CompareAndDelete(ses.ID(), ses) {
+		atomic.AddInt64(&self.count, -1)
+	}
 }
 
 // GetSession 通过会话 ID 获取一个会话
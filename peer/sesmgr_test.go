@@ -0,0 +1,36 @@
+package peer
+
+import (
+	"testing"
+)
+
+func TestSessionManagerStore(t *testing.T) {
+
+	var mgr CoreSessionManager
+
+	raw := new(groupTestSession)
+	mgr.Add(raw)
+
+	// 使用相同 ID 的会话替换，计数不变
+	logical := new(groupTestSession)
+	logical.SetID(raw.ID())
+	mgr.Store(logical)
+
+	if mgr.GetSession(raw.ID()) != logical || mgr.Count() != 1 {
+		t.Fatal("store mismatch", mgr.Count())
+	}
+
+	// 被替换的会话移除时不影响替换后的会话
+	mgr.Remove(raw)
+
+	if mgr.GetSession(logical.ID()) != logical || mgr.Count() != 1 {
+		t.Fatal("replaced session removed", mgr.Count())
+	}
+
+	mgr.Remove(logical)
+	mgr.Remove(logical)
+
+	if mgr.GetSession(logical.ID()) != nil || mgr.Count() != 0 {
+		t.Fatal("remove mismatch", mgr.Count())
+	}
+}
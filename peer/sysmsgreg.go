@@ -30,6 +30,18 @@ func init() {
 		Type:  reflect.TypeOf((*cellnet.SessionConnectError)(nil)).Elem(),
 		ID:    int(util.StringHash("cellnet.SessionConnectError")),
 	})
+	// 注册 SessionReconnecting 消息（准备重连）
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("binary"),
+		Type:  reflect.TypeOf((*cellnet.SessionReconnecting)(nil)).Elem(),
+		ID:    int(util.StringHash("cellnet.SessionReconnecting")),
+	})
+	// 注册 SessionReconnectGiveUp 消息（放弃重连）
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("binary"),
+		Type:  reflect.TypeOf((*cellnet.SessionReconnectGiveUp)(nil)).Elem(),
+		ID:    int(util.StringHash("cellnet.SessionReconnectGiveUp")),
+	})
 	// 注册 SessionClosed 消息（会话已关闭）
 	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
 		Codec: codec.MustGetCodec("binary"),
@@ -3,7 +3,6 @@ package tcp
 import (
 	"net"
 	"sync"
-	"time"
 
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/log"
@@ -12,7 +11,7 @@ import (
 
 // tcpConnector TCP 连接器实现
 // 用于创建 TCP 客户端，连接到服务器
-// 支持自动重连功能
+// 支持自动重连功能，可设置重连策略和备用地址
 type tcpConnector struct {
 	peer.SessionManager      // 会话管理器
 	peer.CorePeerProperty    // 核心 Peer 属性（名称、地址、队列等）
@@ -21,6 +20,7 @@ type tcpConnector struct {
 	peer.CoreProcBundle      // 消息处理组件（编码器、钩子、回调等）
 	peer.CoreTCPSocketOption // TCP Socket 选项（缓冲区、超时等）
 	peer.CoreCaptureIOPanic  // IO 层 panic 捕获控制
+	peer.CoreReconnector     // 重连策略和备用地址
 
 	// defaultSes 默认会话
 	// 连接器通常只有一个会话
@@ -33,11 +33,6 @@ type tcpConnector struct {
 	// sesEndSignal 会话结束信号
 	// 用于等待会话结束
 	sesEndSignal sync.WaitGroup
-
-	// reconDur 重连时间间隔
-	// 当连接断开时，会在此时间后尝试重新连接
-	// 如果为 0，表示不自动重连
-	reconDur time.Duration
 }
 
 // Start 开始连接
@@ -55,7 +50,7 @@ func (self *tcpConnector) Start() cellnet.Peer {
 	}
 
 	// 在后台 goroutine 中执行连接
-	go self.connect(self.Address())
+	go self.connect()
 
 	return self
 }
@@ -89,6 +84,9 @@ func (self *tcpConnector) Stop() {
 	// 标记开始停止
 	self.StartStopping()
 
+	// 打断重连等待
+	self.InterruptReconnect()
+
 	// 关闭会话，触发接收和发送循环退出
 	self.defaultSes.Close()
 
@@ -97,22 +95,6 @@ func (self *tcpConnector) Stop() {
 
 }
 
-// ReconnectDuration 获取重连时间间隔
-// 返回当前设置的重连时间间隔
-// 如果为 0，表示不自动重连
-func (self *tcpConnector) ReconnectDuration() time.Duration {
-
-	return self.reconDur
-}
-
-// SetReconnectDuration 设置重连时间间隔
-// v: 重连时间间隔
-// 当连接断开时，会在此时间后尝试重新连接
-// 如果设置为 0，表示关闭自动重连
-func (self *tcpConnector) SetReconnectDuration(v time.Duration) {
-	self.reconDur = v
-}
-
 // Port 获取本地端口号
 // 返回当前连接使用的本地端口号
 // 如果未连接，返回 0
@@ -132,16 +114,20 @@ func (self *tcpConnector) Port() int {
 const reportConnectFailedLimitTimes = 3
 
 // connect 连接循环
-// address: 服务器地址（格式：host:port）
 // 在后台 goroutine 中运行，持续尝试连接服务器
-// 支持自动重连功能，连接断开后会在指定时间后重新连接
-func (self *tcpConnector) connect(address string) {
+// 支持自动重连功能，连接失败或断开后按重连策略等待并重新连接，可在备用地址之间切换
+func (self *tcpConnector) connect() {
 
 	self.SetRunning(true)
 
+	// 重置重连次数和地址
+	self.ResetReconnect()
+
 	for {
 		self.tryConnTimes++
 
+		address := self.ReconnectAddress(self.Address())
+
 		// 尝试用 Socket 连接地址
 		conn, err := net.Dial("tcp", address)
 
@@ -161,8 +147,8 @@ func (self *tcpConnector) connect(address string) {
 				}
 			}
 
-			// 如果没有设置重连或正在停止，发送连接错误事件并退出
-			if self.ReconnectDuration() == 0 || self.IsStopping() {
+			// 正在停止、没有设置重连或放弃重连时，发送连接错误事件并退出
+			if self.IsStopping() || !self.WaitReconnect(self, self.defaultSes, self.Address(), true) {
 
 				self.ProcEvent(&cellnet.RecvMsgEvent{
 					Ses: self.defaultSes,
@@ -171,9 +157,6 @@ func (self *tcpConnector) connect(address string) {
 				break
 			}
 
-			// 有重连设置，等待后继续尝试
-			time.Sleep(self.ReconnectDuration())
-
 			// 继续连接
 			continue
 		}
@@ -189,6 +172,7 @@ func (self *tcpConnector) connect(address string) {
 
 		// 重置连接尝试次数
 		self.tryConnTimes = 0
+		self.ReconnectSucceeded()
 
 		// 发送连接成功事件
 		self.ProcEvent(&cellnet.RecvMsgEvent{Ses: self.defaultSes, Msg: &cellnet.SessionConnected{}})
@@ -199,14 +183,11 @@ func (self *tcpConnector) connect(address string) {
 		// 清空连接
 		self.defaultSes.setConn(nil)
 
-		// 如果正在停止或没有设置重连，退出循环
-		if self.IsStopping() || self.ReconnectDuration() == 0 {
+		// 如果正在停止、没有设置重连或放弃重连，退出循环
+		if self.IsStopping() || !self.WaitReconnect(self, self.defaultSes, self.Address(), false) {
 			break
 		}
 
-		// 有重连设置，等待后继续尝试
-		time.Sleep(self.ReconnectDuration())
-
 		// 继续连接
 		continue
 
@@ -0,0 +1,101 @@
+package compress
+
+import (
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+	"github.com/bobwong89757/cellnet/proc"
+	"github.com/bobwong89757/cellnet/util"
+)
+
+// DefaultMinSize 默认压缩的最小消息大小，编码后小于此大小的消息不压缩
+const DefaultMinSize = 128
+
+// Transmitter 压缩消息的传输器包装
+// 发送时将编码后不小于 MinSize 的消息使用 zlib 压缩为 CompressedACK，由内层传输器发送
+// 接收时将内层传输器收到的 CompressedACK 解压为原始消息
+// 通信双方都需要使用此包装
+type Transmitter struct {
+	// Inner 内层的消息传输器
+	Inner cellnet.MessageTransmitter
+
+	// MinSize 压缩的最小消息大小
+	MinSize int
+}
+
+// OnRecvMessage 接收消息（实现 MessageTransmitter 接口）
+// 收到 CompressedACK 时解压并解码为原始消息
+func (self *Transmitter) OnRecvMessage(ses cellnet.Session) (msg interface{}, err error) {
+	msg, err = self.Inner.OnRecvMessage(ses)
+	if err != nil {
+		return
+	}
+
+	compressed, ok := msg.(*CompressedACK)
+	if !ok {
+		return
+	}
+
+	data, err := util.DecompressBytes(compressed.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, _, err = codec.DecodeMessage(int(compressed.MsgID), data)
+
+	return
+}
+
+// OnSendMessage 发送消息（实现 MessageTransmitter 接口）
+// 编码后不小于 MinSize 的消息压缩后发送，其他消息以编码后的 *cellnet.RawPacket 交给内层传输器
+// 内层传输器需要支持发送 *cellnet.RawPacket
+func (self *Transmitter) OnSendMessage(ses cellnet.Session, msg interface{}) error {
+	// 系统消息和原始数据包原样发送
+	if cellnet.MessageMetaByMsg(msg) == nil {
+		return self.Inner.OnSendMessage(ses, msg)
+	}
+
+	data, meta, err := codec.EncodeMessage(msg, nil)
+	if err != nil {
+		return err
+	}
+
+	// 使用已编码的数据发送，避免内层传输器再次编码
+	if len(data) < self.MinSize {
+		return self.Inner.OnSendMessage(ses, &cellnet.RawPacket{
+			MsgData: data,
+			MsgID:   meta.ID,
+		})
+	}
+
+	compressed, err := util.CompressBytes(data)
+	if err != nil {
+		return err
+	}
+
+	return self.Inner.OnSendMessage(ses, &CompressedACK{
+		MsgID: uint32(meta.ID),
+		Data:  compressed,
+	})
+}
+
+// Wrap 使用压缩传输器包装内层传输器
+// inner: 内层的消息传输器
+// minSize: 压缩的最小消息大小，小于等于 0 时使用 DefaultMinSize
+func Wrap(inner cellnet.MessageTransmitter, minSize int) cellnet.MessageTransmitter {
+	if minSize <= 0 {
+		minSize = DefaultMinSize
+	}
+
+	return &Transmitter{
+		Inner:   inner,
+		MinSize: minSize,
+	}
+}
+
+// init 注册管线中的 compress 传输器包装
+// 用法如 proc.BindProcessorHandler(peer, "tcp.ltv + compress", callback)
+func init() {
+	proc.RegisterTransmitterWrapper("compress", func(inner cellnet.MessageTransmitter) cellnet.MessageTransmitter {
+		return Wrap(inner, DefaultMinSize)
+	})
+}
@@ -0,0 +1,66 @@
+// Generated by github.com/bobwong89757/protoplus
+// DO NOT EDIT!
+package compress
+
+import (
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+	_ "github.com/bobwong89757/cellnet/codec/protoplus"
+	"github.com/bobwong89757/protoplus/proto"
+	"reflect"
+	"unsafe"
+)
+
+var (
+	_ *proto.Buffer
+	_ codec.CodecRecycler
+	_ cellnet.Session
+	_ reflect.Type
+	_ unsafe.Pointer
+)
+
+type CompressedACK struct {
+	MsgID uint32 // 原始消息ID
+	Data  []byte `text:"-"` // 压缩后的消息数据
+}
+
+func (self *CompressedACK) String() string { return proto.CompactTextString(self) }
+
+func (self *CompressedACK) Size() (ret int) {
+
+	ret += proto.SizeUInt32(0, self.MsgID)
+
+	ret += proto.SizeBytes(1, self.Data)
+
+	return
+}
+
+func (self *CompressedACK) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalUInt32(buffer, 0, self.MsgID)
+
+	proto.MarshalBytes(buffer, 1, self.Data)
+
+	return nil
+}
+
+func (self *CompressedACK) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalUInt32(buffer, wt, &self.MsgID)
+	case 1:
+		return proto.UnmarshalBytes(buffer, wt, &self.Data)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+func init() {
+
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*CompressedACK)(nil)).Elem(),
+		ID:    24573,
+	})
+}
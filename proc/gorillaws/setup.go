@@ -3,6 +3,7 @@ package gorillaws
 import (
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/proc"
+	"github.com/bobwong89757/cellnet/reliable"
 )
 
 func init() {
@@ -14,4 +15,13 @@ func init() {
 		bundle.SetCallback(proc.NewQueuedEventCallback(userCallback))
 
 	})
+
+	// 带可靠会话的处理器，额外参数可以传入 *reliable.Option 进行配置
+	proc.RegisterProcessor("gorillaws.ltv.reliable", func(bundle proc.ProcessorBundle, userCallback cellnet.EventCallback, args ...interface{}) {
+
+		bundle.SetTransmitter(new(WSMessageTransmitter))
+		bundle.SetHooker(proc.NewMultiHooker(reliable.NewHooker(args...), new(MsgHooker)))
+		bundle.SetCallback(proc.NewQueuedEventCallback(userCallback))
+
+	})
 }
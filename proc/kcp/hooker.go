@@ -33,10 +33,10 @@ func (self MsgHooker) OnInboundEvent(inputEvent cellnet.Event) (outputEvent cell
 
 	if !handled {
 
-		inputEvent, handled, err = relay.ResoleveInboundEvent(inputEvent)
+		inputEvent, handled, err = relay.ResolveInboundEventDirect(inputEvent)
 
 		if err != nil {
-			log.GetLog().Errorf("relay.ResoleveInboundEvent:", err)
+			log.GetLog().Errorf("relay.ResolveInboundEventDirect:", err)
 			return
 		}
 
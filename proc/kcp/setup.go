@@ -4,7 +4,6 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"github.com/bobwong89757/cellnet"
-	"github.com/bobwong89757/cellnet/msglog"
 	"github.com/bobwong89757/cellnet/peer/kcp"
 	"github.com/bobwong89757/cellnet/proc"
 )
@@ -31,8 +30,6 @@ func (KCPMessageTransmitter) OnRecvMessage(ses cellnet.Session) (msg interface{}
 
 	msg, err = RecvPacket(data)
 
-	msglog.WriteRecvLogger("kcp", ses, msg)
-
 	return
 }
 
@@ -47,8 +44,6 @@ func (KCPMessageTransmitter) OnSendMessage(ses cellnet.Session, msg interface{})
 
 	writer := ses.(kcp.DataWriter)
 
-	msglog.WriteSendLogger("kcp", ses, msg)
-
 	// ses不再被复用, 所以使用session自己的contextset做内存池, 避免串台
 	return SendPacket(writer, ses.(cellnet.ContextSet), msg)
 }
@@ -58,6 +53,7 @@ func init() {
 	binary.Read(rand.Reader, binary.LittleEndian, &convid)
 	proc.RegisterProcessor("kcp.ltv", func(bundle proc.ProcessorBundle, userCallback cellnet.EventCallback, args ...interface{}) {
 		bundle.SetTransmitter(new(KCPMessageTransmitter))
+		bundle.SetHooker(new(MsgHooker))
 		bundle.SetCallback(userCallback)
 
 	})
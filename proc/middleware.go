@@ -0,0 +1,162 @@
+package proc
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/log"
+)
+
+// Middleware MessageDispatcher 的中间件
+// next: 内层的回调，可能是下一个中间件或消息的处理函数
+// 返回包装后的回调；不调用 next 即可中断处理，如鉴权失败
+// 通过 recover 可以获得处理函数的所有错误（*HandlerError），只有设置了中间件时处理函数的 panic 才会被收集
+//
+// 使用示例:
+//
+//	dispatcher.Use(proc.Recovery(nil), func(next cellnet.EventCallback) cellnet.EventCallback {
+//		return func(ev cellnet.Event) {
+//			if !isAuthorized(ev.Session()) {
+//				return
+//			}
+//
+//			next(ev)
+//		}
+//	})
+type Middleware func(next cellnet.EventCallback) cellnet.EventCallback
+
+// chainMiddlewares 使用中间件包装回调，第一个中间件在最外层
+func chainMiddlewares(middlewares []Middleware, callback cellnet.EventCallback) cellnet.EventCallback {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		callback = middlewares[i](callback)
+	}
+
+	return callback
+}
+
+// HandlerPanic 一个处理函数的 panic
+type HandlerPanic struct {
+	// Value panic 的值
+	Value interface{}
+
+	// Stack panic 时的调用栈
+	Stack []byte
+}
+
+// HandlerError 消息处理函数的错误
+// 只在派发器或消息设置了中间件时使用：同一个消息有多个处理函数时，某个处理函数 panic 不影响之后的处理函数
+// 所有处理函数调用完成后，以 *HandlerError 重新 panic，中间件可以通过 recover 获得所有的错误
+// 没有中间件时处理函数的 panic 原样向外传递
+type HandlerError struct {
+	// Msg 处理的消息
+	Msg interface{}
+
+	// Panics 按处理函数注册顺序排列的 panic
+	Panics []HandlerPanic
+}
+
+// Error 实现 error 接口
+func (self *HandlerError) Error() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "proc: %d handler(s) panic on %s:", len(self.Panics), cellnet.MessageToName(self.Msg))
+
+	for _, p := range self.Panics {
+		fmt.Fprintf(&sb, " %v;", p.Value)
+	}
+
+	return strings.TrimSuffix(sb.String(), ";")
+}
+
+// invokeHandlers 按顺序调用所有处理函数
+// 处理函数 panic 时继续调用之后的处理函数，全部调用完成后以 *HandlerError 重新 panic
+func invokeHandlers(handlers []*Registration, ev cellnet.Event) {
+	var handlerErr *HandlerError
+
+	for _, reg := range handlers {
+		if p := invokeHandler(reg.callback, ev); p != nil {
+			if handlerErr == nil {
+				handlerErr = &HandlerError{Msg: ev.Message()}
+			}
+
+			handlerErr.Panics = append(handlerErr.Panics, *p)
+		}
+	}
+
+	if handlerErr != nil {
+		panic(handlerErr)
+	}
+}
+
+// invokeHandler 调用一个处理函数，返回处理函数的 panic，没有 panic 时返回 nil
+func invokeHandler(callback cellnet.EventCallback, ev cellnet.Event) (ret *HandlerPanic) {
+	defer func() {
+		if v := recover(); v != nil {
+			ret = &HandlerPanic{Value: v, Stack: debug.Stack()}
+		}
+	}()
+
+	callback(ev)
+
+	return nil
+}
+
+// Recovery 捕获处理函数 panic 的中间件
+// onError: 错误通知函数，err 为 *HandlerError 或中间件 panic 的值转换成的 error
+// onError 为 nil 时记录错误日志并关闭 Session
+// 捕获后不再向外层传递 panic，Session 的事件队列可以继续处理后续事件
+func Recovery(onError func(ev cellnet.Event, err error)) Middleware {
+	return func(next cellnet.EventCallback) cellnet.EventCallback {
+		return func(ev cellnet.Event) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+
+				err, ok := v.(error)
+				if !ok {
+					err = fmt.Errorf("%v", v)
+				}
+
+				if onError != nil {
+					onError(ev, err)
+					return
+				}
+
+				if handlerErr, ok := err.(*HandlerError); ok {
+					for _, p := range handlerErr.Panics {
+						log.GetLog().Errorf("#dispatcher.panic %v\n%s", p.Value, p.Stack)
+					}
+				} else {
+					log.GetLog().Errorf("#dispatcher.panic %s\n%s", err, debug.Stack())
+				}
+
+				if ses := ev.Session(); ses != nil {
+					ses.Close()
+				}
+			}()
+
+			next(ev)
+		}
+	}
+}
+
+// Timing 统计处理耗时的中间件
+// report: 耗时通知函数，处理完成（包括 panic）后调用，elapsed 包含内层中间件和处理函数的耗时
+func Timing(report func(ev cellnet.Event, elapsed time.Duration)) Middleware {
+	return func(next cellnet.EventCallback) cellnet.EventCallback {
+		return func(ev cellnet.Event) {
+			begin := time.Now()
+
+			defer func() {
+				report(ev, time.Since(begin))
+			}()
+
+			next(ev)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/bobwong89757/cellnet"
 	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // MessageDispatcher 消息派发器，可选组件
@@ -13,17 +14,45 @@ import (
 type MessageDispatcher struct {
 	// handlerByType 存储消息类型到处理回调函数的映射
 	// 键为消息类型（reflect.Type），值为处理回调函数列表
-	handlerByType map[reflect.Type][]cellnet.EventCallback
+	handlerByType map[reflect.Type][]*Registration
 
-	// handlerByTypeGuard 保护 handlerByType 的读写锁
+	// handlerByTypeGuard 保护 handlerByType、fallback、middlewares 和 middlewareByType 的读写锁
 	// 用于并发安全地访问处理器映射
 	handlerByTypeGuard sync.RWMutex
+
+	// middlewares 派发器的中间件，作用于所有消息
+	middlewares []Middleware
+
+	// middlewareByType 按消息类型存储的中间件
+	middlewareByType map[reflect.Type][]Middleware
+
+	// fallback 没有注册处理函数的消息的处理函数
+	fallback *Registration
+}
+
+// Registration 处理函数的注册记录
+// 由 RegisterMessage 和 Handle 返回，用于注销处理函数
+type Registration struct {
+	// dispatcher 注册到的派发器
+	dispatcher *MessageDispatcher
+
+	// msgType 处理的消息类型
+	msgType reflect.Type
+
+	// callback 处理函数
+	callback cellnet.EventCallback
+}
+
+// Remove 注销处理函数
+// 可以重复调用，正在派发中的消息不受影响
+func (self *Registration) Remove() {
+	self.dispatcher.removeHandler(self)
 }
 
 // OnEvent 处理事件
 // ev: 要处理的事件
 // 根据事件中消息的类型，查找对应的处理回调函数并调用
-// 如果消息类型未注册，则不处理
+// 如果消息类型没有注册处理函数，交给 SetFallback 设置的处理函数（系统事件除外），没有设置时不处理
 func (self *MessageDispatcher) OnEvent(ev cellnet.Event) {
 	// 获取消息的类型
 	msgType := reflect.TypeOf(ev.Message())
@@ -33,17 +62,74 @@ func (self *MessageDispatcher) OnEvent(ev cellnet.Event) {
 		return
 	}
 
-	// 查找该消息类型对应的处理回调函数
+	// 查找该消息类型对应的处理回调函数和中间件
 	self.handlerByTypeGuard.RLock()
-	handlers, ok := self.handlerByType[msgType.Elem()]
+	handlers := self.handlerByType[msgType.Elem()]
+	if len(handlers) == 0 && self.fallback != nil && !isSystemMessage(ev.Message()) {
+		handlers = []*Registration{self.fallback}
+	}
+
+	middlewares := self.middlewares
+	msgMiddlewares := self.middlewareByType[msgType.Elem()]
 	self.handlerByTypeGuard.RUnlock()
 
-	if ok {
-		// 调用所有注册的处理回调函数
-		for _, callback := range handlers {
-			callback(ev)
+	if len(handlers) == 0 {
+		return
+	}
+
+	// 没有中间件时按顺序直接调用，处理函数的 panic 原样向外传递，之后的处理函数不再调用
+	if len(middlewares) == 0 && len(msgMiddlewares) == 0 {
+		for _, reg := range handlers {
+			reg.callback(ev)
 		}
+
+		return
+	}
+
+	// 调用所有注册的处理回调函数
+	callback := func(ev cellnet.Event) {
+		invokeHandlers(handlers, ev)
 	}
+
+	// 由内向外包装：消息的中间件在内，派发器的中间件在外
+	callback = chainMiddlewares(msgMiddlewares, callback)
+	callback = chainMiddlewares(middlewares, callback)
+
+	callback(ev)
+}
+
+// isSystemMessage 检查消息是否为连接建立、断开等系统事件
+func isSystemMessage(msg interface{}) bool {
+	_, ok := msg.(cellnet.SystemMessageIdentifier)
+	return ok
+}
+
+// Use 添加派发器的中间件
+// middlewares: 中间件，作用于派发到此派发器的所有消息
+// 中间件按添加顺序由外向内调用，派发器的中间件在消息的中间件（UseMessage）之外
+func (self *MessageDispatcher) Use(middlewares ...Middleware) {
+	self.handlerByTypeGuard.Lock()
+	defer self.handlerByTypeGuard.Unlock()
+
+	self.middlewares = append(self.middlewares[:len(self.middlewares):len(self.middlewares)], middlewares...)
+}
+
+// UseMessage 添加消息的中间件
+// msgName: 消息的完整名称，格式为 "包名.类型名"
+// middlewares: 中间件，只作用于此消息
+// 中间件按添加顺序由外向内调用
+// 如果消息未注册到消息元信息表，会触发 panic
+func (self *MessageDispatcher) UseMessage(msgName string, middlewares ...Middleware) {
+	meta := cellnet.MessageMetaByFullName(msgName)
+	if meta == nil {
+		panic("message not found:" + msgName)
+	}
+
+	self.handlerByTypeGuard.Lock()
+	defer self.handlerByTypeGuard.Unlock()
+
+	exists := self.middlewareByType[meta.Type]
+	self.middlewareByType[meta.Type] = append(exists[:len(exists):len(exists)], middlewares...)
 }
 
 // Exists 检查消息是否已注册处理函数
@@ -67,44 +153,175 @@ func (self *MessageDispatcher) Exists(msgName string) bool {
 // RegisterMessage 注册消息处理函数
 // msgName: 消息的完整名称，格式为 "包名.类型名"
 // userCallback: 处理该消息的回调函数
+// 返回注册记录，用于注销处理函数
 // 如果消息未注册到消息元信息表，会触发 panic
 // 支持为同一消息类型注册多个处理函数
-func (self *MessageDispatcher) RegisterMessage(msgName string, userCallback cellnet.EventCallback) {
+func (self *MessageDispatcher) RegisterMessage(msgName string, userCallback cellnet.EventCallback) *Registration {
 	// 根据消息名称获取消息元信息
 	meta := cellnet.MessageMetaByFullName(msgName)
 	if meta == nil {
 		panic("message not found:" + msgName)
 	}
 
+	return self.addHandler(meta.Type, userCallback)
+}
+
+// SetFallback 设置没有注册处理函数的消息的处理函数
+// callback: 处理函数，为 nil 时取消，可用于记录或惩罚发送未知消息的客户端
+// 连接建立、断开等系统事件（cellnet.SystemMessageIdentifier）不会交给此处理函数
+// 派发器的中间件同样作用于此处理函数
+func (self *MessageDispatcher) SetFallback(callback cellnet.EventCallback) {
 	self.handlerByTypeGuard.Lock()
-	// 获取该消息类型已有的处理函数列表
-	handlers, _ := self.handlerByType[meta.Type]
-	// 追加新的处理函数
-	handlers = append(handlers, userCallback)
-	// 更新映射表
-	self.handlerByType[meta.Type] = handlers
-	self.handlerByTypeGuard.Unlock()
+	defer self.handlerByTypeGuard.Unlock()
+
+	if callback == nil {
+		self.fallback = nil
+	} else {
+		self.fallback = &Registration{dispatcher: self, callback: callback}
+	}
+}
+
+// addHandler 添加消息类型的处理函数
+func (self *MessageDispatcher) addHandler(msgType reflect.Type, callback cellnet.EventCallback) *Registration {
+	reg := &Registration{
+		dispatcher: self,
+		msgType:    msgType,
+		callback:   callback,
+	}
+
+	self.handlerByTypeGuard.Lock()
+	defer self.handlerByTypeGuard.Unlock()
+
+	// 写时复制，派发中的处理函数列表不受影响
+	handlers := self.handlerByType[msgType]
+	self.handlerByType[msgType] = append(handlers[:len(handlers):len(handlers)], reg)
+
+	return reg
+}
+
+// removeHandler 移除处理函数，最后一个处理函数移除后，消息视为没有注册处理函数
+func (self *MessageDispatcher) removeHandler(reg *Registration) {
+	self.handlerByTypeGuard.Lock()
+	defer self.handlerByTypeGuard.Unlock()
+
+	handlers := self.handlerByType[reg.msgType]
+
+	for index, exist := range handlers {
+		if exist != reg {
+			continue
+		}
+
+		if len(handlers) == 1 {
+			delete(self.handlerByType, reg.msgType)
+		} else {
+			self.handlerByType[reg.msgType] = append(handlers[:index:index], handlers[index+1:]...)
+		}
+
+		return
+	}
+}
+
+// Handle 按消息类型注册处理函数
+// d: 消息派发器
+// callback: 处理函数，msg 为已经转换好类型的消息
+// 返回注册记录，用于注销处理函数
+// 如果 T 未注册到消息元信息表，会触发 panic
+//
+// 使用示例:
+//
+//	proc.Handle(dispatcher, func(ev cellnet.Event, msg *LoginREQ) {
+//		ev.Session().Send(&LoginACK{})
+//	})
+func Handle[T any](d *MessageDispatcher, callback func(ev cellnet.Event, msg *T)) *Registration {
+	msgType := reflect.TypeOf((*T)(nil)).Elem()
+
+	if cellnet.MessageMetaByType(msgType) == nil {
+		panic("message not found:" + msgType.String())
+	}
+
+	return d.addHandler(msgType, func(ev cellnet.Event) {
+		if msg, ok := ev.Message().(*T); ok {
+			callback(ev, msg)
+		}
+	})
 }
 
 // NewMessageDispatcher 创建一个新的消息派发器
 // 返回初始化好的 MessageDispatcher
 func NewMessageDispatcher() *MessageDispatcher {
 	return &MessageDispatcher{
-		handlerByType: make(map[reflect.Type][]cellnet.EventCallback),
+		handlerByType:    make(map[reflect.Type][]*Registration),
+		middlewareByType: make(map[reflect.Type][]Middleware),
 	}
 }
 
+// dispatcherKey Peer 上保存绑定的消息派发器的上下文键
+var dispatcherKey = cellnet.NewContextKey[*atomic.Pointer[MessageDispatcher]]("proc.dispatcher")
+
 // NewMessageDispatcherBindPeer 创建消息派发器并绑定到 Peer
 // peer: 要绑定的 Peer
 // processorName: 处理器的名称，如 "tcp.ltv"、"udp.ltv"
 // 返回创建并绑定好的 MessageDispatcher
 // 这是一个便捷函数，创建派发器并自动绑定到 Peer 的处理器
+// 绑定后可以通过 SwapMessageDispatcher 在运行中替换派发器
 func NewMessageDispatcherBindPeer(peer cellnet.Peer, processorName string) *MessageDispatcher {
 	// 创建消息派发器
 	self := NewMessageDispatcher()
 
-	// 将派发器的 OnEvent 方法绑定到 Peer 的处理器
-	BindProcessorHandler(peer, processorName, self.OnEvent)
+	cs, ok := peer.(cellnet.ContextSet)
+	if !ok {
+		// 将派发器的 OnEvent 方法绑定到 Peer 的处理器
+		BindProcessorHandler(peer, processorName, self.OnEvent)
+		return self
+	}
+
+	// 通过原子指针间接调用，替换派发器时不需要重新绑定处理器
+	current := &atomic.Pointer[MessageDispatcher]{}
+	current.Store(self)
+	dispatcherKey.Set(cs, current)
+
+	BindProcessorHandler(peer, processorName, func(ev cellnet.Event) {
+		current.Load().OnEvent(ev)
+	})
 
 	return self
 }
+
+// PeerMessageDispatcher 获取通过 NewMessageDispatcherBindPeer 绑定到 Peer 的当前派发器
+// 没有绑定时返回 nil
+func PeerMessageDispatcher(peer cellnet.Peer) *MessageDispatcher {
+	cs, ok := peer.(cellnet.ContextSet)
+	if !ok {
+		return nil
+	}
+
+	if current, ok := dispatcherKey.Get(cs); ok && current != nil {
+		return current.Load()
+	}
+
+	return nil
+}
+
+// SwapMessageDispatcher 原子替换通过 NewMessageDispatcherBindPeer 绑定到 Peer 的派发器
+// peer: 已绑定派发器的 Peer
+// dispatcher: 新的派发器，不能为 nil
+// 返回被替换的派发器
+// 已经进入派发的事件仍由旧的派发器处理，之后的事件由新的派发器处理
+// 如果 Peer 没有通过 NewMessageDispatcherBindPeer 绑定派发器，会触发 panic
+func SwapMessageDispatcher(peer cellnet.Peer, dispatcher *MessageDispatcher) *MessageDispatcher {
+	if dispatcher == nil {
+		panic("nil message dispatcher")
+	}
+
+	cs, ok := peer.(cellnet.ContextSet)
+	if !ok {
+		panic("message dispatcher not bound to peer")
+	}
+
+	current, ok := dispatcherKey.Get(cs)
+	if !ok || current == nil {
+		panic("message dispatcher not bound to peer")
+	}
+
+	return current.Swap(dispatcher)
+}
@@ -0,0 +1,309 @@
+package proc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/log"
+	"github.com/bobwong89757/cellnet/msglog"
+)
+
+// HookerCreator 创建管线中事件钩子的函数
+// base: 管线的基础处理器名称，如 "tcp.ltv"，可用于日志等需要区分传输协议的钩子
+type HookerCreator func(base string) cellnet.EventHooker
+
+// PipelineKeptHooker 基础处理器中需要在管线中保留的事件钩子
+// 管线有钩子阶段时替换基础处理器的钩子，实现此接口的钩子会保留，按原有顺序放在管线的钩子之前
+// 如可靠会话的钩子，替换后会失去握手、序列号和重发
+type PipelineKeptHooker interface {
+	cellnet.EventHooker
+
+	// KeepInPipeline 标记在管线中保留
+	KeepInPipeline()
+}
+
+// TransmitterWrapper 包装管线中消息传输器的函数
+// inner: 内层的消息传输器，由基础处理器和之前的包装组成
+// 返回包装后的消息传输器，如压缩、加密
+type TransmitterWrapper func(inner cellnet.MessageTransmitter) cellnet.MessageTransmitter
+
+var (
+	// hookerByName 存储所有已注册的管线钩子
+	hookerByName = map[string]HookerCreator{}
+
+	// wrapperByName 存储所有已注册的管线传输器包装
+	wrapperByName = map[string]TransmitterWrapper{}
+)
+
+// RegisterHooker 注册管线中可以使用的事件钩子
+// name: 钩子的名称，如 "rpc"、"relay"、"msglog"
+// f: 创建钩子的函数，每次绑定管线时调用
+// 如果名称已被钩子或传输器包装使用，会触发 panic
+// 通常在包的 init() 函数中调用
+func RegisterHooker(name string, f HookerCreator) {
+	checkStageName(name)
+
+	hookerByName[name] = f
+}
+
+// RegisterTransmitterWrapper 注册管线中可以使用的传输器包装
+// name: 包装的名称，如 "compress"
+// f: 包装函数，每次绑定管线时调用
+// 如果名称已被钩子或传输器包装使用，会触发 panic
+// 通常在包的 init() 函数中调用
+func RegisterTransmitterWrapper(name string, f TransmitterWrapper) {
+	checkStageName(name)
+
+	wrapperByName[name] = f
+}
+
+// checkStageName 检查管线阶段的名称是否已被使用
+func checkStageName(name string) {
+	if _, ok := hookerByName[name]; ok {
+		panic("duplicate pipeline stage: " + name)
+	}
+
+	if _, ok := wrapperByName[name]; ok {
+		panic("duplicate pipeline stage: " + name)
+	}
+}
+
+// ParsePipeline 解析以 "+" 连接的管线描述
+// spec: 管线描述，如 "tcp.ltv + compress + rpc + relay + msglog"
+// 返回去掉空白的阶段名称列表
+func ParsePipeline(spec string) (stages []string) {
+	for _, stage := range strings.Split(spec, "+") {
+		if stage = strings.TrimSpace(stage); stage != "" {
+			stages = append(stages, stage)
+		}
+	}
+
+	return
+}
+
+// RegisterPipeline 将管线注册为处理器
+// procName: 处理器的名称，之后可以像普通处理器一样通过 BindProcessorHandler 绑定
+// stages: 管线的阶段，格式同 BindProcessorPipeline
+// 阶段在绑定时解析，可以引用之后注册的钩子和传输器包装
+func RegisterPipeline(procName string, stages ...string) {
+	stages = append([]string(nil), stages...)
+
+	RegisterProcessor(procName, func(bundle ProcessorBundle, userCallback cellnet.EventCallback, args ...interface{}) {
+		bindPipeline(bundle, stages, userCallback, args...)
+	})
+}
+
+// BindProcessorPipeline 将管线绑定到 Peer
+// peer: 要绑定处理器的 Peer
+// stages: 管线的阶段，可以直接来自配置，第一个为基础处理器（如 "tcp.ltv"），之后为钩子或传输器包装的名称
+// userCallback: 用户定义的事件处理回调函数
+// args: 可选的额外参数，传递给基础处理器
+// 传输器包装按顺序逐层包装基础处理器的传输器，排在后面的包装在外层，发送时先执行
+// 钩子按顺序组合成 MultiHooker，有钩子时替换基础处理器自带的钩子，没有钩子时保留
+// 基础处理器的钩子中实现 PipelineKeptHooker 的钩子（如 "tcp.ltv.reliable" 的可靠会话钩子）始终保留，放在管线的钩子之前
+// 阶段不存在时会触发 panic
+//
+// 使用示例:
+//
+//	proc.BindProcessorPipeline(peer, []string{"tcp.ltv", "compress", "rpc", "relay", "msglog"}, callback)
+//
+//	// 等价于
+//	proc.BindProcessorHandler(peer, "tcp.ltv + compress + rpc + relay + msglog", callback)
+func BindProcessorPipeline(peer cellnet.Peer, stages []string, userCallback cellnet.EventCallback, args ...interface{}) {
+	bindPipeline(peer.(ProcessorBundle), stages, userCallback, args...)
+}
+
+// bindPipeline 绑定管线到处理器资源包
+func bindPipeline(bundle ProcessorBundle, stages []string, userCallback cellnet.EventCallback, args ...interface{}) {
+	if len(stages) == 0 {
+		panic("empty processor pipeline")
+	}
+
+	base, ok := procByName[stages[0]]
+	if !ok {
+		panic(fmt.Sprintf("processor not found '%s'\ntry to add code below:\nimport (\n  _ \"%s\"\n)\n\n",
+			stages[0],
+			getPackageByCodecName(stages[0])))
+	}
+
+	// 先由基础处理器配置，再按阶段替换
+	var captured pipelineBundle
+	base(&captured, userCallback, args...)
+
+	var hookers []cellnet.EventHooker
+
+	for _, name := range stages[1:] {
+		if creator, ok := hookerByName[name]; ok {
+			hookers = append(hookers, creator(stages[0]))
+		} else if wrapper, ok := wrapperByName[name]; ok {
+			captured.transmit = wrapper(captured.transmit)
+		} else {
+			panic(fmt.Sprintf("pipeline stage not found '%s' in '%s'", name, strings.Join(stages, " + ")))
+		}
+	}
+
+	if len(hookers) > 0 {
+		captured.hooker = NewMultiHooker(append(keptHookers(captured.hooker), hookers...)...)
+	}
+
+	captured.apply(bundle)
+}
+
+// keptHookers 获取基础处理器的钩子中需要在管线中保留的钩子
+func keptHookers(h cellnet.EventHooker) (ret []cellnet.EventHooker) {
+	switch h := h.(type) {
+	case MultiHooker:
+		for _, sub := range h {
+			ret = append(ret, keptHookers(sub)...)
+		}
+	case PipelineKeptHooker:
+		ret = append(ret, h)
+	}
+
+	return
+}
+
+// pipelineBundle 记录基础处理器的配置
+type pipelineBundle struct {
+	transmit cellnet.MessageTransmitter
+	hooker   cellnet.EventHooker
+	callback cellnet.EventCallback
+}
+
+// apply 将记录的配置设置到处理器资源包
+// 支持 ComponentsSetter 时整体替换
+func (self *pipelineBundle) apply(bundle ProcessorBundle) {
+	if setter, ok := bundle.(ComponentsSetter); ok {
+		setter.SetComponents(self.transmit, self.hooker, self.callback)
+		return
+	}
+
+	bundle.SetTransmitter(self.transmit)
+	bundle.SetHooker(self.hooker)
+	bundle.SetCallback(self.callback)
+}
+
+// SetTransmitter 记录消息传输器（实现 ProcessorBundle 接口）
+func (self *pipelineBundle) SetTransmitter(v cellnet.MessageTransmitter) {
+	self.transmit = v
+}
+
+// SetHooker 记录事件钩子（实现 ProcessorBundle 接口）
+func (self *pipelineBundle) SetHooker(v cellnet.EventHooker) {
+	self.hooker = v
+}
+
+// SetCallback 记录用户回调（实现 ProcessorBundle 接口）
+func (self *pipelineBundle) SetCallback(v cellnet.EventCallback) {
+	self.callback = v
+}
+
+// InboundResolver 处理入站事件的函数，如 rpc.ResolveInboundEvent
+// 返回处理后的事件、是否已处理和错误
+type InboundResolver func(inputEvent cellnet.Event) (outputEvent cellnet.Event, handled bool, err error)
+
+// OutboundResolver 处理出站事件的函数，如 rpc.ResolveOutboundEvent
+// 返回是否已处理和错误
+type OutboundResolver func(inputEvent cellnet.Event) (handled bool, err error)
+
+// resolvedSendEvent 已经被管线中的钩子处理并记录日志的出站事件
+type resolvedSendEvent struct {
+	cellnet.Event
+}
+
+// resolverHooker 将 InboundResolver 和 OutboundResolver 转换为事件钩子
+type resolverHooker struct {
+	name     string
+	inbound  InboundResolver
+	outbound OutboundResolver
+}
+
+// OnInboundEvent 处理入站事件（实现 EventHooker 接口）
+// 只处理未被之前的钩子处理的原始事件
+func (self *resolverHooker) OnInboundEvent(inputEvent cellnet.Event) (outputEvent cellnet.Event) {
+	if _, ok := inputEvent.(*cellnet.RecvMsgEvent); !ok || self.inbound == nil {
+		return inputEvent
+	}
+
+	outputEvent, _, err := self.inbound(inputEvent)
+	if err != nil {
+		log.GetLog().Errorf("%s.ResolveInboundEvent: %s", self.name, err)
+		return nil
+	}
+
+	return outputEvent
+}
+
+// OnOutboundEvent 处理出站事件（实现 EventHooker 接口）
+// 处理后的事件被标记，之后的钩子不再重复处理和记录日志
+func (self *resolverHooker) OnOutboundEvent(inputEvent cellnet.Event) (outputEvent cellnet.Event) {
+	if _, ok := inputEvent.(*resolvedSendEvent); ok || self.outbound == nil {
+		return inputEvent
+	}
+
+	handled, err := self.outbound(inputEvent)
+	if err != nil {
+		log.GetLog().Errorf("%s.ResolveOutboundEvent: %s", self.name, err)
+		return nil
+	}
+
+	if handled {
+		return &resolvedSendEvent{Event: inputEvent}
+	}
+
+	return inputEvent
+}
+
+// NewResolverHooker 创建按 tcp.ltv 等处理器自带钩子的规则组合的事件钩子
+// name: 钩子的名称，用于错误日志
+// inbound: 处理入站事件的函数，可以为 nil
+// outbound: 处理出站事件的函数，可以为 nil
+// 管线中多个此类钩子按顺序尝试处理，第一个处理了事件的钩子生效，之后的钩子和 msglog 不再处理
+func NewResolverHooker(name string, inbound InboundResolver, outbound OutboundResolver) cellnet.EventHooker {
+	return &resolverHooker{
+		name:     name,
+		inbound:  inbound,
+		outbound: outbound,
+	}
+}
+
+// msgLogHooker 记录未被其他钩子处理的消息的日志
+type msgLogHooker struct {
+	protocol string
+}
+
+// OnInboundEvent 记录接收日志（实现 EventHooker 接口）
+func (self *msgLogHooker) OnInboundEvent(inputEvent cellnet.Event) (outputEvent cellnet.Event) {
+	if _, ok := inputEvent.(*cellnet.RecvMsgEvent); ok {
+		msglog.WriteRecvLogger(self.protocol, inputEvent.Session(), inputEvent.Message())
+	}
+
+	return inputEvent
+}
+
+// OnOutboundEvent 记录发送日志（实现 EventHooker 接口）
+func (self *msgLogHooker) OnOutboundEvent(inputEvent cellnet.Event) (outputEvent cellnet.Event) {
+	if _, ok := inputEvent.(*resolvedSendEvent); !ok {
+		msglog.WriteSendLogger(self.protocol, inputEvent.Session(), inputEvent.Message())
+	}
+
+	return inputEvent
+}
+
+// logProtocol 根据基础处理器名称获取日志中的协议名称，如 "tcp.ltv" 对应 "tcp"
+func logProtocol(base string) string {
+	protocol := strings.SplitN(base, ".", 2)[0]
+
+	if protocol == "gorillaws" {
+		return "ws"
+	}
+
+	return protocol
+}
+
+func init() {
+	RegisterHooker("msglog", func(base string) cellnet.EventHooker {
+		return &msgLogHooker{protocol: logProtocol(base)}
+	})
+}
@@ -22,16 +22,26 @@ type ProcessorBundle interface {
 	SetCallback(v cellnet.EventCallback)
 }
 
+// ComponentsSetter 支持整体替换消息处理组件的 ProcessorBundle
+// peer.CoreProcBundle 实现了此接口，BindProcessorHandler 通过它在运行中重新绑定处理器
+type ComponentsSetter interface {
+	// SetComponents 同时替换消息传输器、事件钩子和事件回调函数
+	SetComponents(transmit cellnet.MessageTransmitter, hooker cellnet.EventHooker, callback cellnet.EventCallback)
+}
+
 // NewQueuedEventCallback 创建一个队列化的事件回调函数
 // callback: 原始的事件回调函数
 // 返回一个新的 EventCallback，保证回调在 Session 的队列中执行，而不是并发执行
 // 这样可以确保事件处理的顺序性和线程安全性
+// 在队列中执行前已经处理完毕的事件（cellnet.IsEventConsumed）不再交给原始回调
 func NewQueuedEventCallback(callback cellnet.EventCallback) cellnet.EventCallback {
 	return func(ev cellnet.Event) {
 		if callback != nil {
 			// 将回调放入 Session 对应 Peer 的事件队列中执行
 			cellnet.SessionQueuedCall(ev.Session(), func() {
-				callback(ev)
+				if !cellnet.IsEventConsumed(ev) {
+					callback(ev)
+				}
 			})
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/bobwong89757/cellnet"
 	"sort"
+	"strings"
 )
 
 // ProcessorBinder 是处理器绑定函数的类型
@@ -73,14 +74,24 @@ func getPackageByCodecName(name string) string {
 // userCallback: 用户定义的事件处理回调函数
 // args: 可选的额外参数，传递给处理器绑定函数
 // 如果处理器不存在，会触发 panic 并提供导入提示信息
+// 可以在 Peer 运行中重新绑定，传输器、钩子和回调整体替换（需要 Peer 实现 ComponentsSetter，内置 Peer 都已实现）
+// procName 中包含 "+" 时按管线绑定，如 "tcp.ltv + compress + rpc + relay + msglog"，参见 BindProcessorPipeline
 func BindProcessorHandler(peer cellnet.Peer, procName string, userCallback cellnet.EventCallback, args ...interface{}) {
+	// 以 "+" 连接的管线
+	if strings.Contains(procName, "+") {
+		BindProcessorPipeline(peer, ParsePipeline(procName), userCallback, args...)
+		return
+	}
+
 	// 查找处理器
 	if proc, ok := procByName[procName]; ok {
 		// 将 Peer 转换为 ProcessorBundle
 		bundle := peer.(ProcessorBundle)
 
-		// 调用处理器绑定函数，配置消息处理流程
-		proc(bundle, userCallback, args...)
+		// 调用处理器绑定函数，配置消息处理流程，配置完成后整体替换，运行中的 Peer 可以重新绑定
+		var captured pipelineBundle
+		proc(&captured, userCallback, args...)
+		captured.apply(bundle)
 
 	} else {
 		// 处理器不存在，提供友好的错误提示
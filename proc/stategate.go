@@ -0,0 +1,251 @@
+package proc
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/log"
+)
+
+// StateContextKey 消息元信息中允许的 Session 状态的上下文键
+// 值为以逗号分隔的状态名（string）或状态名列表（[]string）
+//
+// 使用示例:
+//
+//	cellnet.MessageMetaByFullName("proto.EnterGameREQ").SetContext(proc.StateContextKey, "Authenticated")
+const StateContextKey = "states"
+
+// StateGate 按 Session 状态限制消息的中间件
+// 为 Session 定义状态（如 Connected → Authenticated → InGame），每个消息只允许在指定的状态下处理
+// 违规的消息被丢弃并计数，同一个 Session 违规次数达到上限时断开连接
+// 消息允许的状态通过 Allow 或消息元信息的上下文（StateContextKey）配置，Allow 优先
+// 系统消息（连接建立、断开等）不受限制
+//
+// 使用示例:
+//
+//	gate := proc.NewStateGate("Connected")
+//	gate.Allow("proto.LoginREQ", "Connected")
+//	gate.Allow("proto.MoveREQ", "InGame")
+//	gate.SetMaxViolations(3)
+//	dispatcher.Use(gate.Middleware())
+//
+//	// 登录成功后
+//	gate.SetState(ses, "Authenticated")
+type StateGate struct {
+	// initial Session 的初始状态
+	initial string
+
+	// stateKey 在 Session 上保存状态的上下文键，每个 StateGate 独立
+	stateKey *cellnet.ContextKey[string]
+
+	// violationKey 在 Session 上保存违规次数的上下文键
+	violationKey *cellnet.ContextKey[*int64]
+
+	// guard 保护以下字段
+	guard sync.RWMutex
+
+	// statesByType 按消息类型存储通过 Allow 配置的允许状态
+	statesByType map[reflect.Type]map[string]bool
+
+	// strict 没有配置允许状态的消息是否拒绝
+	strict bool
+
+	// maxViolations 同一个 Session 违规次数的上限，达到时断开连接，0 表示不断开
+	maxViolations int64
+
+	// onViolation 违规通知函数
+	onViolation func(ev cellnet.Event, state string)
+
+	// violations 所有 Session 的违规总次数
+	violations int64
+}
+
+// Allow 设置消息允许的 Session 状态
+// msgName: 消息的完整名称，格式为 "包名.类型名"
+// states: 允许的状态，覆盖消息元信息中的配置
+// 如果消息未注册到消息元信息表，会触发 panic
+func (self *StateGate) Allow(msgName string, states ...string) {
+	meta := cellnet.MessageMetaByFullName(msgName)
+	if meta == nil {
+		panic("message not found:" + msgName)
+	}
+
+	stateSet := make(map[string]bool, len(states))
+	for _, state := range states {
+		stateSet[state] = true
+	}
+
+	self.guard.Lock()
+	self.statesByType[meta.Type] = stateSet
+	self.guard.Unlock()
+}
+
+// SetStrict 设置没有配置允许状态的消息是否拒绝
+// v: 为 true 时拒绝，默认为 false，即没有配置的消息在任何状态下都允许
+func (self *StateGate) SetStrict(v bool) {
+	self.guard.Lock()
+	self.strict = v
+	self.guard.Unlock()
+}
+
+// SetMaxViolations 设置同一个 Session 违规次数的上限
+// n: 违规次数达到 n 时断开连接，为 1 时第一次违规即断开，为 0 时只丢弃消息（默认）
+func (self *StateGate) SetMaxViolations(n int) {
+	self.guard.Lock()
+	self.maxViolations = int64(n)
+	self.guard.Unlock()
+}
+
+// SetViolationNotify 设置违规通知函数
+// callback: 消息被丢弃时调用，state 为 Session 当时的状态，可用于记录日志或统计
+func (self *StateGate) SetViolationNotify(callback func(ev cellnet.Event, state string)) {
+	self.guard.Lock()
+	self.onViolation = callback
+	self.guard.Unlock()
+}
+
+// SetState 设置 Session 的状态
+// ses: 需要实现 cellnet.ContextSet（所有内置 Session 都已实现）
+func (self *StateGate) SetState(ses cellnet.Session, state string) {
+	self.stateKey.Set(ses.(cellnet.ContextSet), state)
+}
+
+// State 获取 Session 的状态，没有设置时返回初始状态
+func (self *StateGate) State(ses cellnet.Session) string {
+	return self.stateKey.Value(ses.(cellnet.ContextSet), self.initial)
+}
+
+// Violations 获取所有 Session 的违规总次数
+func (self *StateGate) Violations() int64 {
+	return atomic.LoadInt64(&self.violations)
+}
+
+// SessionViolations 获取 Session 的违规次数
+func (self *StateGate) SessionViolations(ses cellnet.Session) int64 {
+	if count, ok := self.violationKey.Get(ses.(cellnet.ContextSet)); ok && count != nil {
+		return atomic.LoadInt64(count)
+	}
+
+	return 0
+}
+
+// Middleware 返回 MessageDispatcher 的中间件
+// 违规的消息不再交给内层的中间件和处理函数
+func (self *StateGate) Middleware() Middleware {
+	return func(next cellnet.EventCallback) cellnet.EventCallback {
+		return func(ev cellnet.Event) {
+			if self.Check(ev) {
+				next(ev)
+			}
+		}
+	}
+}
+
+// Check 检查事件的消息是否允许在 Session 当前的状态下处理
+// 返回 true 表示允许；不允许时计数、通知，违规次数达到上限时断开连接
+// 可以在 MessageDispatcher 之外（如自定义钩子中）直接使用
+func (self *StateGate) Check(ev cellnet.Event) bool {
+	msg := ev.Message()
+	ses := ev.Session()
+
+	if _, ok := msg.(cellnet.SystemMessageIdentifier); ok || msg == nil || ses == nil {
+		return true
+	}
+
+	state := self.State(ses)
+
+	if self.allowed(msg, state) {
+		return true
+	}
+
+	atomic.AddInt64(&self.violations, 1)
+
+	count := self.violationKey.GetOrInit(ses.(cellnet.ContextSet), func() *int64 {
+		return new(int64)
+	})
+
+	sesViolations := atomic.AddInt64(count, 1)
+
+	self.guard.RLock()
+	onViolation := self.onViolation
+	maxViolations := self.maxViolations
+	self.guard.RUnlock()
+
+	if onViolation != nil {
+		onViolation(ev, state)
+	}
+
+	if maxViolations > 0 && sesViolations >= maxViolations {
+		log.GetLog().Warnf("#stategate.kick session %d, msg: %s, state: %s, violations: %d",
+			ses.ID(), cellnet.MessageToName(msg), state, sesViolations)
+
+		ses.Close()
+	}
+
+	return false
+}
+
+// allowed 检查消息是否允许在状态下处理
+func (self *StateGate) allowed(msg interface{}, state string) bool {
+	meta := cellnet.MessageMetaByMsg(msg)
+
+	self.guard.RLock()
+	strict := self.strict
+	stateSet, ok := self.statesByType[metaType(meta)]
+	self.guard.RUnlock()
+
+	if ok {
+		return stateSet[state]
+	}
+
+	if meta != nil {
+		if raw, exists := meta.GetContext(StateContextKey); exists {
+			return containsState(raw, state)
+		}
+	}
+
+	return !strict
+}
+
+// metaType 获取消息元信息的类型，元信息为 nil 时返回 nil
+func metaType(meta *cellnet.MessageMeta) reflect.Type {
+	if meta == nil {
+		return nil
+	}
+
+	return meta.Type
+}
+
+// containsState 检查消息元信息中配置的允许状态是否包含 state
+func containsState(raw interface{}, state string) bool {
+	switch v := raw.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if strings.TrimSpace(s) == state {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range v {
+			if s == state {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NewStateGate 创建按 Session 状态限制消息的中间件
+// initial: Session 的初始状态，如 "Connected"
+func NewStateGate(initial string) *StateGate {
+	return &StateGate{
+		initial:      initial,
+		stateKey:     cellnet.NewContextKey[string]("proc.sessionState"),
+		violationKey: cellnet.NewContextKey[*int64]("proc.stateViolations"),
+		statesByType: map[reflect.Type]map[string]bool{},
+	}
+}
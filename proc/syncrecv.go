@@ -1,21 +1,54 @@
 package proc
 
 import (
-	"github.com/bobwong89757/cellnet"
+	"context"
+	"errors"
+	"fmt"
 	"reflect"
 	"sync"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/log"
 )
 
+// ErrUnexpectedMessage Expect 期望的消息没有按顺序到达
+var ErrUnexpectedMessage = errors.New("proc: Unexpected message")
+
 // SyncReceiver 同步接收消息器，可选组件
 // 可作为流程测试辅助工具，用于同步等待消息到达
-// 所有接收到的消息都会通过通道传递，可以同步等待特定消息
+// 接收到的事件先缓存，等待时从缓存中取出第一个匹配的事件，不匹配的事件保留，之后的等待仍可以取到
+// 因此先到达的其他消息不会丢失，也不会阻塞 Peer 的事件处理
+// 缓存默认不限制大小，长时间运行时可以通过 SetCapacity 限制缓存的事件数量，或通过 Drain 清空缓存
+//
+// 使用示例:
+//
+//	rv := proc.NewSyncReceiver(p)
+//	proc.BindProcessorHandler(p, "tcp.ltv", rv.EventCallback())
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+//	defer cancel()
+//
+//	if _, err := rv.WaitMessage(ctx, "cellnet.SessionConnected"); err != nil {
+//		t.Fatal(err)
+//	}
 type SyncReceiver struct {
-	// evChan 事件通道
-	// 用于传递接收到的消息事件
-	evChan chan cellnet.Event
+	// guard 保护以下字段
+	guard sync.Mutex
+
+	// pending 已到达但还未被取出的事件，按到达顺序排列
+	pending []cellnet.Event
+
+	// arrived 有新事件到达时关闭并替换，用于唤醒等待
+	arrived chan struct{}
+
+	// capacity 缓存的最大事件数量，小于等于 0 时不限制
+	capacity int
+
+	// dropped 缓存已满时丢弃的事件数量
+	dropped int
 
 	// callback 事件回调函数
-	// 当消息到达时，会将事件发送到 evChan
+	// 当消息到达时，会将事件放入缓存
 	callback func(ev cellnet.Event)
 }
 
@@ -25,46 +58,199 @@ func (self *SyncReceiver) EventCallback() cellnet.EventCallback {
 	return self.callback
 }
 
+// SetCapacity 设置缓存的最大事件数量
+// capacity: 最大事件数量，小于等于 0 时不限制
+// 缓存已满时丢弃最早到达的事件，并输出错误日志，可以通过 Dropped 获取丢弃的数量
+// 返回自身以便链式调用
+func (self *SyncReceiver) SetCapacity(capacity int) *SyncReceiver {
+	self.guard.Lock()
+	self.capacity = capacity
+	self.guard.Unlock()
+	return self
+}
+
+// Dropped 获取缓存已满时丢弃的事件数量
+func (self *SyncReceiver) Dropped() int {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return self.dropped
+}
+
+// push 缓存到达的事件并唤醒等待
+// 缓存已满时丢弃最早到达的事件
+func (self *SyncReceiver) push(ev cellnet.Event) {
+	self.guard.Lock()
+	if self.capacity > 0 && len(self.pending) >= self.capacity {
+		log.GetLog().Errorf("#syncrecv.drop pending full(%d), msg: %s", self.capacity, cellnet.MessageToName(self.pending[0].Message()))
+
+		self.pending[0] = nil
+		self.pending = self.pending[1:]
+		self.dropped++
+	}
+
+	self.pending = append(self.pending, ev)
+	close(self.arrived)
+	self.arrived = make(chan struct{})
+	self.guard.Unlock()
+}
+
+// take 从缓存中取出第一个匹配的事件
+// 没有匹配的事件时返回 nil 和用于等待新事件的通道
+func (self *SyncReceiver) take(match func(ev cellnet.Event) bool) (cellnet.Event, <-chan struct{}) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	for i, ev := range self.pending {
+		if match == nil || match(ev) {
+			self.pending = append(self.pending[:i], self.pending[i+1:]...)
+			return ev, nil
+		}
+	}
+
+	return nil, self.arrived
+}
+
+// Pending 获取已到达但还未被取出的事件数量
+func (self *SyncReceiver) Pending() int {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return len(self.pending)
+}
+
+// Drain 取出缓存中所有的事件，按到达顺序排列
+// 可用于流程的阶段之间丢弃不再关心的事件，或检查是否有遗漏处理的事件
+func (self *SyncReceiver) Drain() []cellnet.Event {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	evs := self.pending
+	self.pending = nil
+	return evs
+}
+
 // Recv 持续阻塞，直到某个消息到达后，使用回调返回消息
 // callback: 消息到达时的回调函数
 // 返回自身以便链式调用
-// 此方法会阻塞当前 goroutine，直到有消息到达
+// 按到达顺序取出缓存中的第一个事件，缓存为空时阻塞当前 goroutine，直到有消息到达
+// 没有超时，消息不到达时永远阻塞，测试中建议使用 RecvContext
 func (self *SyncReceiver) Recv(callback cellnet.EventCallback) *SyncReceiver {
-	// 从通道接收事件并调用回调
-	callback(<-self.evChan)
+	self.RecvContext(context.Background(), callback)
 	return self
 }
 
-// WaitMessage 持续阻塞，直到某个指定类型的消息到达后，返回消息
+// RecvContext 阻塞直到某个消息到达，使用回调返回消息
+// ctx: 等待的上下文，取消或超过截止时间时不调用回调，返回 ctx.Err()
+// callback: 消息到达时的回调函数
+// 按到达顺序取出缓存中的第一个事件
+func (self *SyncReceiver) RecvContext(ctx context.Context, callback cellnet.EventCallback) error {
+	ev, err := self.WaitEvent(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	callback(ev)
+	return nil
+}
+
+// WaitEvent 阻塞直到满足条件的事件到达，返回事件
+// ctx: 等待的上下文，取消或超过截止时间时返回 ctx.Err()
+// match: 事件的匹配条件，为 nil 时匹配任意事件
+// 已经到达的事件按到达顺序优先匹配，不满足条件的事件保留在缓存中
+func (self *SyncReceiver) WaitEvent(ctx context.Context, match func(ev cellnet.Event) bool) (cellnet.Event, error) {
+	for {
+		ev, arrived := self.take(match)
+		if ev != nil {
+			return ev, nil
+		}
+
+		select {
+		case <-arrived:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// WaitMessage 阻塞直到指定类型的消息到达，返回消息
+// ctx: 等待的上下文，取消或超过截止时间时返回的错误包含 ctx.Err()
 // msgName: 消息的完整名称，格式为 "包名.类型名"
-// 返回接收到的消息对象
+// 先于指定消息到达的其他消息保留在缓存中，之后的等待仍可以取到
 // 如果消息名称未注册，会触发 panic
-// 此方法会阻塞当前 goroutine，直到匹配的消息到达
-func (self *SyncReceiver) WaitMessage(msgName string) (msg interface{}) {
-	var wg sync.WaitGroup
+func (self *SyncReceiver) WaitMessage(ctx context.Context, msgName string) (msg interface{}, err error) {
+	ev, err := self.WaitEvent(ctx, MatchMessage(msgName))
+	if err != nil {
+		return nil, fmt.Errorf("proc: wait message '%s': %w", msgName, err)
+	}
 
-	// 根据消息名称获取消息元信息
-	meta := cellnet.MessageMetaByFullName(msgName)
-	if meta == nil {
-		panic("unknown message name:" + msgName)
+	return ev.Message(), nil
+}
+
+// Expect 按顺序等待一组消息，返回按相同顺序排列的消息
+// ctx: 等待整组消息的上下文，取消或超过截止时间时返回的错误包含 ctx.Err()
+// msgNames: 期望的消息的完整名称，按期望到达的顺序排列
+// 期望的消息先于之前的消息到达时返回 ErrUnexpectedMessage，不在期望中的消息被忽略并保留在缓存中
+// 出错时返回已经按顺序收到的消息
+// 如果消息名称未注册，会触发 panic
+//
+// 使用示例:
+//
+//	msgs, err := rv.Expect(ctx, "proto.LoginACK", "proto.EnterGameACK")
+func (self *SyncReceiver) Expect(ctx context.Context, msgNames ...string) (msgs []interface{}, err error) {
+	metas := make([]*cellnet.MessageMeta, len(msgNames))
+	for i, msgName := range msgNames {
+		metas[i] = messageMetaByName(msgName)
 	}
 
-	wg.Add(1)
+	for i, msgName := range msgNames {
+		remaining := metas[i:]
 
-	// 接收消息，直到匹配到指定类型
-	self.Recv(func(ev cellnet.Event) {
-		// 检查消息类型是否匹配
-		inMeta := cellnet.MessageMetaByType(reflect.TypeOf(ev.Message()))
-		if inMeta == meta {
-			// 类型匹配，保存消息并通知等待
-			msg = ev.Message()
-			wg.Done()
+		ev, err := self.WaitEvent(ctx, func(ev cellnet.Event) bool {
+			inMeta := cellnet.MessageMetaByType(reflect.TypeOf(ev.Message()))
+
+			for _, meta := range remaining {
+				if inMeta == meta {
+					return true
+				}
+			}
+
+			return false
+		})
+
+		if err != nil {
+			return msgs, fmt.Errorf("proc: expect message '%s' (%d of %d): %w", msgName, i+1, len(msgNames), err)
 		}
-	})
 
-	// 等待匹配的消息到达
-	wg.Wait()
-	return
+		if got := cellnet.MessageMetaByType(reflect.TypeOf(ev.Message())); got != metas[i] {
+			return msgs, fmt.Errorf("%w: expect '%s' (%d of %d), got '%s'", ErrUnexpectedMessage, msgName, i+1, len(msgNames), got.FullName())
+		}
+
+		msgs = append(msgs, ev.Message())
+	}
+
+	return msgs, nil
+}
+
+// MatchMessage 创建匹配指定类型消息的条件，可用于 WaitEvent
+// msgName: 消息的完整名称，格式为 "包名.类型名"
+// 如果消息名称未注册，会触发 panic
+func MatchMessage(msgName string) func(ev cellnet.Event) bool {
+	meta := messageMetaByName(msgName)
+
+	return func(ev cellnet.Event) bool {
+		return cellnet.MessageMetaByType(reflect.TypeOf(ev.Message())) == meta
+	}
+}
+
+// messageMetaByName 根据消息名称获取消息元信息，未注册时触发 panic
+func messageMetaByName(msgName string) *cellnet.MessageMeta {
+	meta := cellnet.MessageMetaByFullName(msgName)
+	if meta == nil {
+		panic("unknown message name:" + msgName)
+	}
+
+	return meta
 }
 
 // NewSyncReceiver 新建同步消息接收器
@@ -73,13 +259,11 @@ func (self *SyncReceiver) WaitMessage(msgName string) (msg interface{}) {
 // 需要将返回的 EventCallback 绑定到 Peer 的处理器才能接收消息
 func NewSyncReceiver(p cellnet.Peer) *SyncReceiver {
 	self := &SyncReceiver{
-		evChan: make(chan cellnet.Event),
+		arrived: make(chan struct{}),
 	}
 
-	// 创建回调函数，将事件发送到通道
-	self.callback = func(ev cellnet.Event) {
-		self.evChan <- ev
-	}
+	// 创建回调函数，将事件放入缓存
+	self.callback = self.push
 
 	return self
 }
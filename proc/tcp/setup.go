@@ -3,6 +3,7 @@ package tcp
 import (
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/proc"
+	"github.com/bobwong89757/cellnet/reliable"
 )
 
 // init 包初始化函数
@@ -20,4 +21,16 @@ func init() {
 		bundle.SetCallback(proc.NewQueuedEventCallback(userCallback))
 
 	})
+
+	// 注册带可靠会话的消息处理器为 tcp.ltv.reliable
+	// 断线重连后恢复逻辑会话，并重发未确认的消息
+	// 额外参数可以传入 *reliable.Option 进行配置
+	proc.RegisterProcessor("tcp.ltv.reliable", func(bundle proc.ProcessorBundle, userCallback cellnet.EventCallback, args ...interface{}) {
+
+		bundle.SetTransmitter(new(TCPMessageTransmitter))
+		// 可靠会话钩子需要最先处理，将物理会话上的事件转换为逻辑会话上的事件
+		bundle.SetHooker(proc.NewMultiHooker(reliable.NewHooker(args...), new(MsgHooker)))
+		bundle.SetCallback(proc.NewQueuedEventCallback(userCallback))
+
+	})
 }
@@ -11,17 +11,33 @@ import (
 // sendPacket 发送 UDP 数据包
 // writer: 数据写入器
 // ctx: 上下文集合，用于内存池等资源管理
-// msg: 要发送的消息
+// msg: 要发送的消息，也可以是 *cellnet.RawPacket
 // UDP 数据包格式：[包体大小(2字节)][消息ID(2字节)][消息数据]
 // 返回发送错误
 func sendPacket(writer udp.DataWriter, ctx cellnet.ContextSet, msg interface{}) error {
 
-	// 将用户数据转换为字节数组和消息 ID
-	msgData, meta, err := codec.EncodeMessage(msg, ctx)
+	var (
+		msgData []byte
+		msgID   int
+		meta    *cellnet.MessageMeta
+	)
 
-	if err != nil {
-		log.GetLog().Errorf("send message encode error: %s", err)
-		return err
+	switch m := msg.(type) {
+	case *cellnet.RawPacket: // 发裸包
+		msgData = m.MsgData
+		msgID = m.MsgID
+	default: // 发普通编码包
+		var err error
+
+		// 将用户数据转换为字节数组和消息 ID
+		msgData, meta, err = codec.EncodeMessage(msg, ctx)
+
+		if err != nil {
+			log.GetLog().Errorf("send message encode error: %s", err)
+			return err
+		}
+
+		msgID = meta.ID
 	}
 
 	// 创建数据包缓冲区（包头 + 消息数据）
@@ -31,7 +47,7 @@ func sendPacket(writer udp.DataWriter, ctx cellnet.ContextSet, msg interface{})
 	binary.LittleEndian.PutUint16(pktData, uint16(HeaderSize+len(msgData)))
 
 	// 写入消息 ID（Type）
-	binary.LittleEndian.PutUint16(pktData[2:], uint16(meta.ID))
+	binary.LittleEndian.PutUint16(pktData[2:], uint16(msgID))
 
 	// 写入消息数据（Value）
 	copy(pktData[HeaderSize:], msgData)
@@ -40,7 +56,9 @@ func sendPacket(writer udp.DataWriter, ctx cellnet.ContextSet, msg interface{})
 	writer.WriteData(pktData)
 
 	// 释放编码器资源（内存池等）
-	codec.FreeCodecResource(meta.Codec, msgData, ctx)
+	if meta != nil {
+		codec.FreeCodecResource(meta.Codec, msgData, ctx)
+	}
 
 	return nil
 }
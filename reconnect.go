@@ -0,0 +1,61 @@
+package cellnet
+
+import "time"
+
+// ReconnectPolicy 定义连接器的重连策略
+// 连接断开或连接失败后，连接器通过重连策略决定等待多久再次连接，以及何时放弃
+type ReconnectPolicy interface {
+	// NextDelay 返回第 attempt 次重连前需要等待的时间
+	// attempt: 重连次数，从 1 开始，连接成功后重新计数
+	// 返回 false 表示放弃重连
+	NextDelay(attempt int) (time.Duration, bool)
+}
+
+// FailoverMode 表示多个连接地址之间的切换方式
+type FailoverMode int32
+
+const (
+	// FailoverPriority 按优先级切换
+	// 每次重连都从第一个地址开始，失败后依次尝试后续地址
+	FailoverPriority FailoverMode = iota
+
+	// FailoverRoundRobin 轮询切换
+	// 每次重连都使用上一次地址的下一个地址，使多个连接器分散到各个地址
+	FailoverRoundRobin
+)
+
+// String 返回切换方式的字符串表示
+func (self FailoverMode) String() string {
+	switch self {
+	case FailoverPriority:
+		return "Priority"
+	case FailoverRoundRobin:
+		return "RoundRobin"
+	}
+
+	return "Unknown"
+}
+
+// Reconnector 定义支持重连策略和备用地址的连接器接口
+// tcp.Connector、gorillaws.Connector 和 kcp.Connector 实现了此接口
+// 使用示例:
+//
+//	p.(cellnet.Reconnector).SetReconnectPolicy(&peer.BackoffReconnectPolicy{
+//		Min:    time.Second,
+//		Max:    time.Minute,
+//		Jitter: 0.5,
+//	})
+type Reconnector interface {
+	// SetReconnectPolicy 设置重连策略
+	// policy: 重连策略，nil 表示不自动重连
+	// 会覆盖 SetReconnectDuration 的设置
+	SetReconnectPolicy(policy ReconnectPolicy)
+
+	// ReconnectPolicy 获取当前的重连策略
+	ReconnectPolicy() ReconnectPolicy
+
+	// SetFailoverAddress 设置备用连接地址
+	// mode: 地址切换方式
+	// addrs: 备用地址列表，排在 Peer 的 Address 之后
+	SetFailoverAddress(mode FailoverMode, addrs ...string)
+}
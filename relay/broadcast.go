@@ -1,5 +1,11 @@
 package relay
 
+import (
+	"sync"
+
+	"github.com/bobwong89757/cellnet"
+)
+
 // BroadcasterFunc 定义广播函数的类型
 // 当接收到 relay 消息时，会调用此函数进行广播
 // event: 接收到的 relay 消息事件
@@ -13,6 +19,146 @@ var bcFunc BroadcasterFunc
 // callback: 广播回调函数
 // 回调时，会在对应 Peer/Session 所在的队列中调用，保证线程安全
 // 用于处理接收到的 relay 消息，可以实现消息广播、转发等功能
+// 全局广播函数对进程内所有 Peer 生效，在 Peer 的广播函数（AddBroadcaster）之后调用
 func SetBroadcaster(callback BroadcasterFunc) {
 	bcFunc = callback
 }
+
+// PeerBroadcasterFunc 定义 Peer 广播函数的类型
+// event: 接收到的 relay 消息事件
+// 返回 true 继续处理；返回 false 停止处理，之后的广播函数、全局广播函数和用户回调都不再收到此消息
+type PeerBroadcasterFunc func(event *RecvMsgEvent) bool
+
+// peerBroadcaster 注册到 Peer 的一个广播函数
+type peerBroadcaster struct {
+	// callback 广播函数
+	callback PeerBroadcasterFunc
+
+	// metas 关注的消息类型，为空时接收所有消息
+	metas []*cellnet.MessageMeta
+}
+
+// match 检查广播函数是否关注此消息
+func (self *peerBroadcaster) match(msg interface{}) bool {
+	if len(self.metas) == 0 {
+		return true
+	}
+
+	meta := cellnet.MessageMetaByMsg(msg)
+	if meta == nil {
+		return false
+	}
+
+	for _, m := range self.metas {
+		if m == meta {
+			return true
+		}
+	}
+
+	return false
+}
+
+// broadcasterList Peer 上注册的广播函数列表
+type broadcasterList struct {
+	// guard 保护 list
+	guard sync.RWMutex
+
+	// list 按注册顺序排列的广播函数
+	list []*peerBroadcaster
+}
+
+// snapshot 获取广播函数列表的副本，调用广播函数时不持有锁
+func (self *broadcasterList) snapshot() []*peerBroadcaster {
+	self.guard.RLock()
+	defer self.guard.RUnlock()
+
+	return self.list
+}
+
+// broadcastersKey Peer 上保存广播函数列表的上下文键
+var broadcastersKey = cellnet.NewContextKey[*broadcasterList]("relay.broadcasters")
+
+// AddBroadcaster 为 Peer 注册广播函数
+// p: 接收 relay 消息的 Peer，需要实现 cellnet.ContextSet（所有内置 Peer 都已实现）
+// callback: 广播函数，在用户回调之前调用，与用户回调在同一个 goroutine 中：队列化回调的处理器（如 tcp.ltv）在 Peer 的队列中调用，kcp.ltv、udp.ltv 在收包的 goroutine 中调用
+// msgs: 关注的消息类型，传入消息对象或 nil 指针，如 (*LoginREQ)(nil)，为空时接收所有消息
+// 返回注销函数，可以重复调用
+// 多个广播函数按注册顺序调用，返回 false 时停止后续处理；同一进程中的网关和后端 Peer 可以注册不同的广播函数
+// 消息类型未注册到消息元信息表时，会触发 panic
+//
+// 使用示例:
+//
+//	remove := relay.AddBroadcaster(gatewayAcceptor, func(ev *relay.RecvMsgEvent) bool {
+//		broadcastToClients(ev)
+//		return false
+//	}, (*ChatACK)(nil))
+func AddBroadcaster(p cellnet.Peer, callback PeerBroadcasterFunc, msgs ...interface{}) (remove func()) {
+	cs, ok := p.(cellnet.ContextSet)
+	if !ok {
+		panic("relay: peer not implement cellnet.ContextSet")
+	}
+
+	bc := &peerBroadcaster{
+		callback: callback,
+	}
+
+	for _, msg := range msgs {
+		meta := cellnet.MessageMetaByMsg(msg)
+		if meta == nil {
+			panic("relay: message not registered: " + cellnet.MessageToName(msg))
+		}
+
+		bc.metas = append(bc.metas, meta)
+	}
+
+	bcList := broadcastersKey.GetOrInit(cs, func() *broadcasterList {
+		return &broadcasterList{}
+	})
+
+	// 写时复制，调用中的广播函数列表不受影响
+	bcList.guard.Lock()
+	bcList.list = append(bcList.list[:len(bcList.list):len(bcList.list)], bc)
+	bcList.guard.Unlock()
+
+	return func() {
+		bcList.guard.Lock()
+		defer bcList.guard.Unlock()
+
+		for index, exist := range bcList.list {
+			if exist == bc {
+				bcList.list = append(bcList.list[:index:index], bcList.list[index+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// peerBroadcasters 获取 Peer 上注册的广播函数，没有时返回 nil
+func peerBroadcasters(ses cellnet.Session) []*peerBroadcaster {
+	cs, ok := ses.Peer().(cellnet.ContextSet)
+	if !ok {
+		return nil
+	}
+
+	bcList, ok := broadcastersKey.Get(cs)
+	if !ok || bcList == nil {
+		return nil
+	}
+
+	return bcList.snapshot()
+}
+
+// broadcast 依次调用 Peer 的广播函数和全局广播函数
+// 在用户回调之前调用，广播函数返回 false 时将事件标记为已处理，不再交给用户回调
+func broadcast(broadcasters []*peerBroadcaster, ev *RecvMsgEvent) {
+	for _, bc := range broadcasters {
+		if bc.match(ev.Msg) && !bc.callback(ev) {
+			ev.consume()
+			return
+		}
+	}
+
+	if bcFunc != nil {
+		bcFunc(ev)
+	}
+}
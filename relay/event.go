@@ -1,6 +1,8 @@
 package relay
 
 import (
+	"sync/atomic"
+
 	"github.com/bobwong89757/cellnet"
 )
 
@@ -13,8 +15,51 @@ type RecvMsgEvent struct {
 	// ack 接收到的 RelayACK 消息
 	ack *RelayACK
 
+	// headers 接收到的透传头部，来自 RelayBatchACK
+	headers []RelayHeader
+
 	// Msg 解码后的消息对象
 	Msg interface{}
+
+	// consumed 广播函数已经停止处理此消息
+	consumed atomic.Bool
+}
+
+// BatchEvent 表示批量转发的 relay 消息事件
+// 一个 RelayBatchACK 携带多个消息时产生，实现了 cellnet.EventBatch 接口
+// Peer 按顺序将每个消息作为 RecvMsgEvent 分别交给用户回调，这些事件共享同一份透传数据
+type BatchEvent struct {
+	// Ses 接收消息的 Session
+	Ses cellnet.Session
+
+	// batch 接收到的 RelayBatchACK 消息
+	batch *RelayBatchACK
+
+	// events 按顺序展开的消息事件
+	events []*RecvMsgEvent
+}
+
+// Session 返回事件对应的 Session
+// 实现 cellnet.Event 接口
+func (self *BatchEvent) Session() cellnet.Session {
+	return self.Ses
+}
+
+// Message 返回接收到的 RelayBatchACK 消息
+// 实现 cellnet.Event 接口
+func (self *BatchEvent) Message() interface{} {
+	return self.batch
+}
+
+// Events 返回展开后的消息事件
+// 实现 cellnet.EventBatch 接口
+func (self *BatchEvent) Events() []cellnet.Event {
+	ret := make([]cellnet.Event, len(self.events))
+	for i, ev := range self.events {
+		ret[i] = ev
+	}
+
+	return ret
 }
 
 // PassThroughAsInt64 获取透传的 int64 数据
@@ -47,6 +92,48 @@ func (self *RecvMsgEvent) PassThroughAsString() string {
 	return self.ack.Str
 }
 
+// Header 获取透传头部
+// 返回透传头部的副本，修改后可以作为 Relay 的参数继续转发，没有时返回空的头部
+func (self *RecvMsgEvent) Header() *Header {
+	return newHeaderFromEntries(self.headers)
+}
+
+// HeaderInt64 获取透传头部中的 int64 值
+// 返回值和是否存在，键不存在或值不是 int64 时返回 false
+func (self *RecvMsgEvent) HeaderInt64(key string) (int64, bool) {
+	entry, ok := self.headerEntry(key, headerKindInt64)
+	return entry.Int64, ok
+}
+
+// HeaderStr 获取透传头部中的字符串值
+// 返回值和是否存在，键不存在或值不是字符串时返回 false
+func (self *RecvMsgEvent) HeaderStr(key string) (string, bool) {
+	entry, ok := self.headerEntry(key, headerKindStr)
+	return entry.Str, ok
+}
+
+// HeaderBytes 获取透传头部中的 []byte 值
+// 返回值和是否存在，键不存在或值不是 []byte 时返回 false
+func (self *RecvMsgEvent) HeaderBytes(key string) ([]byte, bool) {
+	entry, ok := self.headerEntry(key, headerKindBytes)
+	return entry.Bytes, ok
+}
+
+// headerEntry 查找指定键和类型的透传头部，同一个键有多个时以最后一个为准
+func (self *RecvMsgEvent) headerEntry(key string, kind uint32) (ret RelayHeader, ok bool) {
+	for _, entry := range self.headers {
+		if entry.Key == key {
+			ret, ok = entry, entry.Kind == kind
+		}
+	}
+
+	if !ok {
+		ret = RelayHeader{}
+	}
+
+	return
+}
+
 // Session 返回事件对应的 Session
 // 实现 cellnet.Event 接口
 func (self *RecvMsgEvent) Session() cellnet.Session {
@@ -59,11 +146,22 @@ func (self *RecvMsgEvent) Message() interface{} {
 	return self.Msg
 }
 
+// Consumed 消息是否已经被广播函数停止处理
+// 实现 cellnet.ConsumableEvent 接口，已停止处理的消息不再交给用户回调
+func (self *RecvMsgEvent) Consumed() bool {
+	return self.consumed.Load()
+}
+
+// consume 标记消息已经被广播函数停止处理
+func (self *RecvMsgEvent) consume() {
+	self.consumed.Store(true)
+}
+
 // Reply 消息原路返回
 // msg: 要回复的消息对象
-// 会将消息和透传数据一起发送回原 Session
+// 会将消息和透传数据、透传头部一起发送回原 Session
 // 注意：没填的值不会被发送
 func (self *RecvMsgEvent) Reply(msg interface{}) {
 	// 没填的值不会被发送
-	Relay(self.Ses, msg, self.ack.Int64, self.ack.Int64Slice, self.ack.Str)
+	Relay(self.Ses, msg, self.ack.Int64, self.ack.Int64Slice, self.ack.Str, self.Header())
 }
@@ -0,0 +1,193 @@
+package relay
+
+import (
+	"sort"
+)
+
+// 透传头部值的类型，对应 RelayHeader.Kind
+const (
+	headerKindInt64 uint32 = iota + 1
+	headerKindStr
+	headerKindBytes
+)
+
+// Header relay 消息的透传头部
+// 以字符串为键，值可以是 int64、string 或 []byte
+// 作为 Relay 的参数随消息发送，接收方通过 RecvMsgEvent.Header 获取，Reply 时原样带回
+// 用于携带用户 ID、追踪 ID、网关 ID、语言等信息
+//
+// 使用示例:
+//
+//	header := relay.NewHeader().SetInt64("uid", 10001).SetStr("trace", traceID)
+//	relay.Relay(ses, msg, header)
+//
+// 零值可以直接使用，读取时 nil 头部视为空头部
+type Header struct {
+	entryByKey map[string]RelayHeader
+}
+
+// SetInt64 设置 int64 值
+// 返回自身，便于链式调用
+func (self *Header) SetInt64(key string, v int64) *Header {
+	self.set(RelayHeader{Key: key, Kind: headerKindInt64, Int64: v})
+	return self
+}
+
+// SetStr 设置字符串值
+// 返回自身，便于链式调用
+func (self *Header) SetStr(key string, v string) *Header {
+	self.set(RelayHeader{Key: key, Kind: headerKindStr, Str: v})
+	return self
+}
+
+// SetBytes 设置 []byte 值
+// 返回自身，便于链式调用
+func (self *Header) SetBytes(key string, v []byte) *Header {
+	self.set(RelayHeader{Key: key, Kind: headerKindBytes, Bytes: v})
+	return self
+}
+
+// set 设置头部值，第一次设置时创建映射表
+func (self *Header) set(entry RelayHeader) {
+	if self.entryByKey == nil {
+		self.entryByKey = map[string]RelayHeader{}
+	}
+
+	self.entryByKey[entry.Key] = entry
+}
+
+// entry 获取键对应的头部值，nil 头部视为空头部
+func (self *Header) entry(key string) (entry RelayHeader, ok bool) {
+	if self == nil {
+		return
+	}
+
+	entry, ok = self.entryByKey[key]
+	return
+}
+
+// Int64 获取 int64 值
+// 返回值和是否存在，键不存在或值不是 int64 时返回 false
+func (self *Header) Int64(key string) (int64, bool) {
+	entry, ok := self.entry(key)
+	if !ok || entry.Kind != headerKindInt64 {
+		return 0, false
+	}
+
+	return entry.Int64, true
+}
+
+// Str 获取字符串值
+// 返回值和是否存在，键不存在或值不是字符串时返回 false
+func (self *Header) Str(key string) (string, bool) {
+	entry, ok := self.entry(key)
+	if !ok || entry.Kind != headerKindStr {
+		return "", false
+	}
+
+	return entry.Str, true
+}
+
+// Bytes 获取 []byte 值
+// 返回值和是否存在，键不存在或值不是 []byte 时返回 false
+func (self *Header) Bytes(key string) ([]byte, bool) {
+	entry, ok := self.entry(key)
+	if !ok || entry.Kind != headerKindBytes {
+		return nil, false
+	}
+
+	return entry.Bytes, true
+}
+
+// Has 检查键是否存在
+func (self *Header) Has(key string) bool {
+	_, ok := self.entry(key)
+	return ok
+}
+
+// Delete 删除键
+// 返回自身，便于链式调用
+func (self *Header) Delete(key string) *Header {
+	if self != nil {
+		delete(self.entryByKey, key)
+	}
+
+	return self
+}
+
+// Keys 获取所有的键，按字典序排列
+func (self *Header) Keys() []string {
+	keys := make([]string, 0, self.Len())
+	if self == nil {
+		return keys
+	}
+
+	for key := range self.entryByKey {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Len 获取键的数量
+func (self *Header) Len() int {
+	if self == nil {
+		return 0
+	}
+
+	return len(self.entryByKey)
+}
+
+// Clone 复制头部
+// 修改副本不影响原头部，用于转发时在收到的头部上追加信息
+// nil 头部返回新的空头部
+func (self *Header) Clone() *Header {
+	ret := NewHeader()
+	if self == nil {
+		return ret
+	}
+
+	for key, entry := range self.entryByKey {
+		ret.entryByKey[key] = entry
+	}
+
+	return ret
+}
+
+// entries 转换为 RelayBatchACK 中传输的头部列表，按键排序
+func (self *Header) entries() []RelayHeader {
+	if self.Len() == 0 {
+		return nil
+	}
+
+	ret := make([]RelayHeader, 0, len(self.entryByKey))
+	for _, key := range self.Keys() {
+		ret = append(ret, self.entryByKey[key])
+	}
+
+	return ret
+}
+
+// NewHeader 创建空的透传头部
+func NewHeader() *Header {
+	return &Header{
+		entryByKey: map[string]RelayHeader{},
+	}
+}
+
+// newHeaderFromEntries 从 RelayBatchACK 中传输的头部列表创建透传头部
+// 不认识的值类型会被忽略
+func newHeaderFromEntries(entries []RelayHeader) *Header {
+	self := NewHeader()
+
+	for _, entry := range entries {
+		switch entry.Kind {
+		case headerKindInt64, headerKindStr, headerKindBytes:
+			self.entryByKey[entry.Key] = entry
+		}
+	}
+
+	return self
+}
@@ -84,6 +84,190 @@ func (self *RelayACK) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt prot
 	return proto.ErrUnknownField
 }
 
+type RelayBatchACK struct {
+	Entries    []RelayEntry `text:"-"` // 按顺序转发的消息
+	Bytes      []byte       `text:"-"` // 数据bytes
+	Int64      int64        // 透传int64
+	Int64Slice []int64      // 透传int64切片
+	Str        string
+	Headers    []RelayHeader // 透传头部
+}
+
+func (self *RelayBatchACK) String() string { return proto.CompactTextString(self) }
+
+func (self *RelayBatchACK) Size() (ret int) {
+
+	if len(self.Entries) > 0 {
+		for _, elm := range self.Entries {
+			ret += proto.SizeStruct(0, &elm)
+		}
+	}
+
+	ret += proto.SizeBytes(1, self.Bytes)
+
+	ret += proto.SizeInt64(2, self.Int64)
+
+	ret += proto.SizeInt64Slice(3, self.Int64Slice)
+
+	ret += proto.SizeString(4, self.Str)
+
+	if len(self.Headers) > 0 {
+		for _, elm := range self.Headers {
+			ret += proto.SizeStruct(5, &elm)
+		}
+	}
+
+	return
+}
+
+func (self *RelayBatchACK) Marshal(buffer *proto.Buffer) error {
+
+	for _, elm := range self.Entries {
+		proto.MarshalStruct(buffer, 0, &elm)
+	}
+
+	proto.MarshalBytes(buffer, 1, self.Bytes)
+
+	proto.MarshalInt64(buffer, 2, self.Int64)
+
+	proto.MarshalInt64Slice(buffer, 3, self.Int64Slice)
+
+	proto.MarshalString(buffer, 4, self.Str)
+
+	for _, elm := range self.Headers {
+		proto.MarshalStruct(buffer, 5, &elm)
+	}
+
+	return nil
+}
+
+func (self *RelayBatchACK) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		var elm RelayEntry
+		if err := proto.UnmarshalStruct(buffer, wt, &elm); err != nil {
+			return err
+		} else {
+			self.Entries = append(self.Entries, elm)
+			return nil
+		}
+	case 1:
+		return proto.UnmarshalBytes(buffer, wt, &self.Bytes)
+	case 2:
+		return proto.UnmarshalInt64(buffer, wt, &self.Int64)
+	case 3:
+		return proto.UnmarshalInt64Slice(buffer, wt, &self.Int64Slice)
+	case 4:
+		return proto.UnmarshalString(buffer, wt, &self.Str)
+	case 5:
+		var elm RelayHeader
+		if err := proto.UnmarshalStruct(buffer, wt, &elm); err != nil {
+			return err
+		} else {
+			self.Headers = append(self.Headers, elm)
+			return nil
+		}
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+type RelayEntry struct {
+	MsgID uint32 // 消息ID
+	Msg   []byte // 数据消息转换后传输bytes
+}
+
+func (self *RelayEntry) String() string { return proto.CompactTextString(self) }
+
+func (self *RelayEntry) Size() (ret int) {
+
+	ret += proto.SizeUInt32(0, self.MsgID)
+
+	ret += proto.SizeBytes(1, self.Msg)
+
+	return
+}
+
+func (self *RelayEntry) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalUInt32(buffer, 0, self.MsgID)
+
+	proto.MarshalBytes(buffer, 1, self.Msg)
+
+	return nil
+}
+
+func (self *RelayEntry) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalUInt32(buffer, wt, &self.MsgID)
+	case 1:
+		return proto.UnmarshalBytes(buffer, wt, &self.Msg)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+type RelayHeader struct {
+	Key   string // 键
+	Kind  uint32 // 值的类型
+	Int64 int64  // int64值
+	Str   string // 字符串值
+	Bytes []byte `text:"-"` // bytes值
+}
+
+func (self *RelayHeader) String() string { return proto.CompactTextString(self) }
+
+func (self *RelayHeader) Size() (ret int) {
+
+	ret += proto.SizeString(0, self.Key)
+
+	ret += proto.SizeUInt32(1, self.Kind)
+
+	ret += proto.SizeInt64(2, self.Int64)
+
+	ret += proto.SizeString(3, self.Str)
+
+	ret += proto.SizeBytes(4, self.Bytes)
+
+	return
+}
+
+func (self *RelayHeader) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalString(buffer, 0, self.Key)
+
+	proto.MarshalUInt32(buffer, 1, self.Kind)
+
+	proto.MarshalInt64(buffer, 2, self.Int64)
+
+	proto.MarshalString(buffer, 3, self.Str)
+
+	proto.MarshalBytes(buffer, 4, self.Bytes)
+
+	return nil
+}
+
+func (self *RelayHeader) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalString(buffer, wt, &self.Key)
+	case 1:
+		return proto.UnmarshalUInt32(buffer, wt, &self.Kind)
+	case 2:
+		return proto.UnmarshalInt64(buffer, wt, &self.Int64)
+	case 3:
+		return proto.UnmarshalString(buffer, wt, &self.Str)
+	case 4:
+		return proto.UnmarshalBytes(buffer, wt, &self.Bytes)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
 func init() {
 
 	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
@@ -91,4 +275,9 @@ func init() {
 		Type:  reflect.TypeOf((*RelayACK)(nil)).Elem(),
 		ID:    45545,
 	})
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*RelayBatchACK)(nil)).Elem(),
+		ID:    24715,
+	})
 }
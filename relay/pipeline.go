@@ -0,0 +1,25 @@
+package relay
+
+import (
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/proc"
+)
+
+// init 注册管线中的 relay 钩子
+// 用法如 proc.BindProcessorHandler(peer, "tcp.ltv + relay + msglog", callback)
+// 基础处理器直接调用用户回调（如 kcp.ltv、udp.ltv）时，广播函数同步调用
+func init() {
+	proc.RegisterHooker("relay", func(base string) cellnet.EventHooker {
+		if directCallbackProcessors[base] {
+			return proc.NewResolverHooker("relay", ResolveInboundEventDirect, ResolveOutboundEvent)
+		}
+
+		return proc.NewResolverHooker("relay", ResoleveInboundEvent, ResolveOutboundEvent)
+	})
+}
+
+// directCallbackProcessors 直接调用用户回调、不使用队列化回调的处理器
+var directCallbackProcessors = map[string]bool{
+	"kcp.ltv": true,
+	"udp.ltv": true,
+}
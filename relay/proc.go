@@ -23,79 +23,153 @@ type PassthroughContent struct {
 // ResoleveInboundEvent 处理入站的 relay 消息
 // inputEvent: 输入的接收事件
 // 返回处理后的输出事件、是否已处理、错误信息
-// 如果消息是 RelayACK 类型，会解码消息并调用广播函数
+// 如果消息是 RelayACK 或 RelayBatchACK 类型，会解码消息并调用 Peer 的广播函数和全局广播函数
+// RelayBatchACK 携带多个消息时返回 BatchEvent，由 Peer 按顺序展开为多个 RecvMsgEvent
+// 广播函数投递到 Session 的队列中调用，用于队列化用户回调的处理器（如 tcp.ltv）
 func ResoleveInboundEvent(inputEvent cellnet.Event) (ouputEvent cellnet.Event, handled bool, err error) {
+	return resolveInboundEvent(inputEvent, false)
+}
+
+// ResolveInboundEventDirect 处理入站的 relay 消息，广播函数在当前 goroutine 中同步调用
+// 用于直接调用用户回调、不使用队列的处理器（如 kcp.ltv、udp.ltv）
+// 广播函数在返回前调用完毕，停止处理的消息不再交给之后直接调用的用户回调
+func ResolveInboundEventDirect(inputEvent cellnet.Event) (ouputEvent cellnet.Event, handled bool, err error) {
+	return resolveInboundEvent(inputEvent, true)
+}
+
+// resolveInboundEvent 处理入站的 relay 消息
+// direct: 为 true 时同步调用广播函数，否则投递到 Session 的队列中调用
+func resolveInboundEvent(inputEvent cellnet.Event, direct bool) (ouputEvent cellnet.Event, handled bool, err error) {
+	var events []*RecvMsgEvent
+
 	switch relayMsg := inputEvent.Message().(type) {
 	case *RelayACK:
-		// 创建接收事件
-		ev := &RecvMsgEvent{
-			Ses: inputEvent.Session(),
-			ack: relayMsg,
+		// 即使没有消息 ID 也创建事件（只有原始数据或透传数据）
+		ev, err := newRecvMsgEvent(inputEvent.Session(), relayMsg, relayMsg.MsgID, relayMsg.Msg)
+		if err != nil {
+			return inputEvent, false, err
 		}
 
-		// 如果有消息 ID，解码消息
-		if relayMsg.MsgID != 0 {
-			ev.Msg, _, err = codec.DecodeMessage(int(relayMsg.MsgID), relayMsg.Msg)
+		events = append(events, ev)
+	case *RelayBatchACK:
+		// 批量转发的消息共享同一份透传数据和透传头部
+		ack := &RelayACK{
+			Bytes:      relayMsg.Bytes,
+			Int64:      relayMsg.Int64,
+			Int64Slice: relayMsg.Int64Slice,
+			Str:        relayMsg.Str,
+		}
+
+		for _, entry := range relayMsg.Entries {
+			ev, err := newRecvMsgEvent(inputEvent.Session(), ack, entry.MsgID, entry.Msg)
 			if err != nil {
-				return
+				return inputEvent, false, err
+			}
+
+			events = append(events, ev)
+		}
+
+		// 没有消息时与 RelayACK 一致，只有原始数据或透传数据
+		if len(events) == 0 {
+			ev, err := newRecvMsgEvent(inputEvent.Session(), ack, 0, nil)
+			if err != nil {
+				return inputEvent, false, err
 			}
+
+			events = append(events, ev)
 		}
 
-		// 如果消息日志有效，记录接收日志
-		if msglog.IsMsgLogValid(int(relayMsg.MsgID)) {
-			peerInfo := inputEvent.Session().Peer().(cellnet.PeerProperty)
-
-			log.GetLog().Debugf("#relay.recv(%s)@%d len: %d %s {%s}| %s",
-				peerInfo.Name(),
-				inputEvent.Session().ID(),
-				cellnet.MessageSize(ev.Message()),
-				cellnet.MessageToName(ev.Message()),
-				cellnet.MessageToString(relayMsg),
-				cellnet.MessageToString(ev.Message()))
+		for _, ev := range events {
+			ev.headers = relayMsg.Headers
 		}
+	default:
+		// 不是 relay 消息，不处理
+		return inputEvent, false, nil
+	}
 
-		// 如果有广播函数，在队列中调用
-		if bcFunc != nil {
-			// 转到对应线程中调用，保证线程安全
-			cellnet.SessionQueuedCall(inputEvent.Session(), func() {
-				bcFunc(ev)
-			})
+	// 如果有广播函数，在用户回调之前按顺序调用，广播函数停止处理的消息不再交给用户回调
+	broadcasters := peerBroadcasters(inputEvent.Session())
+	if len(broadcasters) > 0 || bcFunc != nil {
+		broadcastAll := func() {
+			for _, ev := range events {
+				broadcast(broadcasters, ev)
+			}
 		}
 
-		return ev, true, nil
+		if direct {
+			// 用户回调直接调用，广播函数也在当前 goroutine 中调用
+			broadcastAll()
+		} else {
+			// 先于用户回调投递到队列，转到对应线程中调用，保证线程安全
+			cellnet.SessionQueuedCall(inputEvent.Session(), broadcastAll)
+		}
 	}
 
-	// 不是 relay 消息，不处理
-	return inputEvent, false, nil
+	if len(events) == 1 {
+		return events[0], true, nil
+	}
+
+	return &BatchEvent{
+		Ses:    inputEvent.Session(),
+		batch:  inputEvent.Message().(*RelayBatchACK),
+		events: events,
+	}, true, nil
+}
+
+// newRecvMsgEvent 解码 relay 消息并创建接收事件
+// ses: 接收消息的 Session
+// relayMsg: 提供透传数据的 RelayACK 消息，批量转发时由 RelayBatchACK 的透传数据创建
+// msgID: 消息 ID，为 0 时没有消息
+// data: 消息数据
+// 返回创建的事件和解码错误
+func newRecvMsgEvent(ses cellnet.Session, relayMsg *RelayACK, msgID uint32, data []byte) (ev *RecvMsgEvent, err error) {
+	// 创建接收事件
+	ev = &RecvMsgEvent{
+		Ses: ses,
+		ack: relayMsg,
+	}
+
+	// 如果有消息 ID，解码消息
+	if msgID != 0 {
+		ev.Msg, _, err = codec.DecodeMessage(int(msgID), data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 如果消息日志有效，记录接收日志
+	if msglog.IsMsgLogValid(int(msgID)) {
+		peerInfo := ses.Peer().(cellnet.PeerProperty)
+
+		log.GetLog().Debugf("#relay.recv(%s)@%d len: %d %s {%s}| %s",
+			peerInfo.Name(),
+			ses.ID(),
+			cellnet.MessageSize(ev.Message()),
+			cellnet.MessageToName(ev.Message()),
+			cellnet.MessageToString(relayMsg),
+			cellnet.MessageToString(ev.Message()))
+	}
+
+	return ev, nil
 }
 
 // ResolveOutboundEvent 处理 relay.Relay 出站消息的日志
 // inputEvent: 输入的发送事件
 // 返回是否已处理、错误信息
-// 如果消息是 RelayACK 类型，会记录发送日志
+// 如果消息是 RelayACK 或 RelayBatchACK 类型，会记录发送日志，批量转发时每个消息记录一条
 func ResolveOutboundEvent(inputEvent cellnet.Event) (handled bool, err error) {
 	switch relayMsg := inputEvent.Message().(type) {
 	case *RelayACK:
-		// 如果消息日志有效，记录发送日志
-		if msglog.IsMsgLogValid(int(relayMsg.MsgID)) {
-			var payload interface{}
-			// 如果有消息 ID，解码消息用于日志
-			if relayMsg.MsgID != 0 {
-				payload, _, err = codec.DecodeMessage(int(relayMsg.MsgID), relayMsg.Msg)
-				if err != nil {
-					return
-				}
-			}
-
-			peerInfo := inputEvent.Session().Peer().(cellnet.PeerProperty)
+		if err = writeSendLog(inputEvent.Session(), relayMsg, relayMsg.MsgID, relayMsg.Msg); err != nil {
+			return
+		}
 
-			log.GetLog().Debugf("#relay.send(%s)@%d len: %d %s {%s}| %s",
-				peerInfo.Name(),
-				inputEvent.Session().ID(),
-				cellnet.MessageSize(payload),
-				cellnet.MessageToName(payload),
-				cellnet.MessageToString(relayMsg),
-				cellnet.MessageToString(payload))
+		return true, nil
+	case *RelayBatchACK:
+		for _, entry := range relayMsg.Entries {
+			if err = writeSendLog(inputEvent.Session(), relayMsg, entry.MsgID, entry.Msg); err != nil {
+				return
+			}
 		}
 
 		return true, nil
@@ -103,3 +177,37 @@ func ResolveOutboundEvent(inputEvent cellnet.Event) (handled bool, err error) {
 
 	return
 }
+
+// writeSendLog 记录 relay 消息的发送日志
+// ses: 发送消息的 Session
+// relayMsg: 发送的 RelayACK 或 RelayBatchACK 消息
+// msgID: 消息 ID，为 0 时没有消息
+// data: 消息数据
+// 返回解码错误
+func writeSendLog(ses cellnet.Session, relayMsg interface{}, msgID uint32, data []byte) (err error) {
+	// 如果消息日志有效，记录发送日志
+	if !msglog.IsMsgLogValid(int(msgID)) {
+		return nil
+	}
+
+	var payload interface{}
+	// 如果有消息 ID，解码消息用于日志
+	if msgID != 0 {
+		payload, _, err = codec.DecodeMessage(int(msgID), data)
+		if err != nil {
+			return
+		}
+	}
+
+	peerInfo := ses.Peer().(cellnet.PeerProperty)
+
+	log.GetLog().Debugf("#relay.send(%s)@%d len: %d %s {%s}| %s",
+		peerInfo.Name(),
+		ses.ID(),
+		cellnet.MessageSize(payload),
+		cellnet.MessageToName(payload),
+		cellnet.MessageToString(relayMsg),
+		cellnet.MessageToString(payload))
+
+	return nil
+}
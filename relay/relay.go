@@ -9,19 +9,21 @@ import (
 
 var (
 	// ErrInvalidPeerSession 表示无效的 Peer 或 Session 错误
-	ErrInvalidPeerSession = errors.New("Require valid cellnet.Session or cellnet.TCPConnector")
+	ErrInvalidPeerSession = errors.New("Require valid cellnet.Session, cellnet.TCPConnector or cellnet.UDPConnector")
 )
 
 // Relay 转发消息到指定的 Session
-// sesDetector: Session 或 TCPConnector，用于确定目标 Session
+// sesDetector: Session、TCPConnector 或 UDPConnector，用于确定目标 Session
 // dataList: 要转发的数据列表，支持以下类型：
-//   - 消息对象（会被编码）
+//   - 消息对象（会被编码），可以有多个，接收方按顺序展开为多个 RecvMsgEvent
 //   - []byte（作为原始数据）
 //   - int64（作为透传数据）
 //   - []int64（作为透传数据）
 //   - string（作为透传数据）
+//   - *Header（作为透传头部，为 nil 时忽略）
 // 返回转发错误，如果成功则返回 nil
-// 注意：只能转发一个消息对象，多个消息对象会触发 panic
+// 只有一个消息对象且没有透传头部时使用 RelayACK 发送，与旧版本的格式一致
+// 多个消息对象或有透传头部时在一个 RelayBatchACK 中发送，共享同一份透传数据，接收方需要支持 RelayBatchACK
 func Relay(sesDetector interface{}, dataList ...interface{}) error {
 	// 获取 Session
 	ses, err := getSession(sesDetector)
@@ -32,6 +34,12 @@ func Relay(sesDetector interface{}, dataList ...interface{}) error {
 
 	var ack RelayACK
 
+	// 所有消息对象，按参数顺序排列
+	var entries []RelayEntry
+
+	// 透传头部
+	var headers []RelayHeader
+
 	// 处理所有数据
 	for _, payload := range dataList {
 		switch value := payload.(type) {
@@ -45,27 +53,48 @@ func Relay(sesDetector interface{}, dataList ...interface{}) error {
 		case string:
 			// 透传字符串数据
 			ack.Str = value
+		case *Header:
+			// 透传头部，nil 头部忽略
+			if value != nil {
+				headers = value.entries()
+			}
 		case []byte:
 			// 作为原始数据 payload
 			ack.Bytes = value
 		default:
 			// 消息对象，需要编码
-			if ack.MsgID == 0 {
-				var meta *cellnet.MessageMeta
-				// 编码消息
-				ack.Msg, meta, err = codec.EncodeMessage(payload, nil)
-
-				if err != nil {
-					return err
-				}
-
-				ack.MsgID = uint32(meta.ID)
-			} else {
-				// 不支持多个消息对象
-				panic("Multi message relay not support")
+			data, meta, err := codec.EncodeMessage(payload, nil)
+
+			if err != nil {
+				return err
 			}
+
+			entries = append(entries, RelayEntry{
+				MsgID: uint32(meta.ID),
+				Msg:   data,
+			})
 		}
 	}
+
+	// 多个消息或有透传头部时使用 RelayBatchACK 发送
+	if len(entries) > 1 || len(headers) > 0 {
+		ses.Send(&RelayBatchACK{
+			Entries:    entries,
+			Bytes:      ack.Bytes,
+			Int64:      ack.Int64,
+			Int64Slice: ack.Int64Slice,
+			Str:        ack.Str,
+			Headers:    headers,
+		})
+
+		return nil
+	}
+
+	if len(entries) == 1 {
+		ack.Msg = entries[0].Msg
+		ack.MsgID = entries[0].MsgID
+	}
+
 	// 发送转发消息
 	ses.Send(&ack)
 
@@ -73,7 +102,7 @@ func Relay(sesDetector interface{}, dataList ...interface{}) error {
 }
 
 // getSession 从检测器获取 Session
-// sesDetector: Session 或 TCPConnector
+// sesDetector: Session、TCPConnector 或 UDPConnector
 // 返回对应的 Session 和错误信息
 // 如果类型不支持，返回错误
 func getSession(sesDetector interface{}) (cellnet.Session, error) {
@@ -84,6 +113,9 @@ func getSession(sesDetector interface{}) (cellnet.Session, error) {
 	case cellnet.TCPConnector:
 		// TCPConnector，获取其 Session
 		return unknown.Session(), nil
+	case cellnet.UDPConnector:
+		// UDP 和 KCP 连接器，获取其 Session
+		return unknown.Session(), nil
 	default:
 		// 不支持的类型
 		return nil, ErrInvalidPeerSession
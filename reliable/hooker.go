@@ -0,0 +1,423 @@
+package reliable
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+	"github.com/bobwong89757/cellnet/log"
+	"github.com/bobwong89757/cellnet/peer"
+)
+
+// Option 可靠会话的配置
+// 通过 proc.BindProcessorHandler 的额外参数传入，如:
+//
+//	proc.BindProcessorHandler(p, "tcp.ltv.reliable", callback, &reliable.Option{GracePeriod: time.Minute})
+type Option struct {
+	// GracePeriod 服务器端物理连接断开后，逻辑会话的保留时间
+	// 宽限期内客户端重连可以恢复会话，超时后触发 cellnet.SessionClosed 事件
+	// 默认 30 秒，小于 0 表示不保留，断开时立即触发 cellnet.SessionClosed 事件
+	GracePeriod time.Duration
+
+	// MaxReplay 重发缓冲的最大消息数量
+	// 超过后丢弃最旧的消息，对方缺少被丢弃的消息时无法恢复会话
+	// 默认 1024
+	MaxReplay int
+
+	// AckEvery 收到多少个消息后发送一次独立的确认消息
+	// 发送消息时会捎带确认，默认 16
+	AckEvery int
+
+	// AckDelay 收到消息后最迟多久发送独立的确认消息
+	// 消息较少、达不到 AckEvery 时，对方的重发缓冲依靠此确认释放
+	// 默认 200 毫秒，小于 0 表示只按 AckEvery 确认
+	AckDelay time.Duration
+}
+
+// Hooker 可靠会话的事件钩子
+// 实现 cellnet.EventHooker 接口，需要放在处理链的最前面
+// 将物理连接上的握手、序列号和确认消息转换为逻辑会话上的事件：
+//   - 服务器端：握手完成后触发 cellnet.SessionAccepted，恢复时触发 SessionResumed，
+//     物理连接断开并超过宽限期后触发 cellnet.SessionClosed
+//   - 客户端：握手完成后触发 cellnet.SessionConnected，恢复时触发 SessionResumed，
+//     物理连接断开时立即触发 cellnet.SessionClosed，逻辑会话保留用于重连恢复
+//
+// 逻辑会话绑定物理会话后，在 Peer 的会话管理器中替代物理会话，
+// 因此可以通过逻辑会话 ID 从 Peer 获取逻辑会话，会话分组也作用于逻辑会话
+// 服务器端的逻辑会话在宽限期内保留在会话管理器中，客户端的逻辑会话断开时移除
+type Hooker struct {
+	opt Option
+
+	// guard 保护以下映射表的互斥锁
+	guard sync.Mutex
+
+	// sesByRaw 物理会话到逻辑会话的映射
+	sesByRaw map[cellnet.Session]*Session
+
+	// sesByToken 服务器端恢复令牌到逻辑会话的映射
+	sesByToken map[string]*Session
+
+	// sesByPeer 客户端连接器到逻辑会话的映射
+	// 连接器重连时复用物理会话对象，因此按 Peer 记录
+	sesByPeer map[cellnet.Peer]*Session
+}
+
+// OnInboundEvent 处理入站事件（实现 EventHooker 接口）
+// 返回逻辑会话上的事件，协议内部消息返回 nil
+func (self *Hooker) OnInboundEvent(inputEvent cellnet.Event) (outputEvent cellnet.Event) {
+
+	raw := inputEvent.Session()
+
+	// 已经是逻辑会话上的事件，如宽限期到期的关闭事件
+	if _, ok := raw.(*Session); ok {
+		return inputEvent
+	}
+
+	switch msg := inputEvent.Message().(type) {
+	case *cellnet.SessionAccepted:
+		// 等待客户端握手
+		return nil
+	case *cellnet.SessionConnected:
+		self.onConnected(raw)
+		return nil
+	case *HandshakeREQ:
+		return self.onHandshakeREQ(raw, msg)
+	case *HandshakeACK:
+		return self.onHandshakeACK(raw, msg)
+	case *SeqMsg:
+		ses := self.sessionByRaw(raw)
+		if ses == nil || !ses.recv(msg) {
+			return nil
+		}
+
+		userMsg, _, err := codec.DecodeMessage(int(msg.MsgID), msg.Data)
+		if err != nil {
+			log.GetLog().Errorf("reliable.recv decode error: %s", err)
+			return nil
+		}
+
+		return &cellnet.RecvMsgEvent{Ses: ses, Msg: userMsg}
+	case *SeqAck:
+		if ses := self.sessionByRaw(raw); ses != nil {
+			ses.ack(msg.Ack)
+		}
+
+		return nil
+	case *cellnet.SessionClosed:
+		return self.onClosed(raw, msg)
+	}
+
+	if ses := self.sessionByRaw(raw); ses != nil {
+		return &cellnet.RecvMsgEvent{Ses: ses, Msg: inputEvent.Message()}
+	}
+
+	// 连接错误等没有逻辑会话的事件
+	if _, ok := inputEvent.Message().(cellnet.SystemMessageIdentifier); ok {
+		return inputEvent
+	}
+
+	log.GetLog().Warnf("reliable: drop message before handshake, sesid: %d, %s", raw.ID(), cellnet.MessageToName(inputEvent.Message()))
+	return nil
+}
+
+// KeepInPipeline 标记在处理器管线中保留（实现 proc.PipelineKeptHooker 接口）
+// 如 "tcp.ltv.reliable + rpc" 中，替换基础处理器的钩子时仍然保留可靠会话
+func (self *Hooker) KeepInPipeline() {}
+
+// OnOutboundEvent 处理出站事件（实现 EventHooker 接口）
+// 逻辑会话在发送时已经完成封装，直接返回
+func (self *Hooker) OnOutboundEvent(inputEvent cellnet.Event) (outputEvent cellnet.Event) {
+	return inputEvent
+}
+
+// sessionByRaw 根据物理会话获取逻辑会话
+func (self *Hooker) sessionByRaw(raw cellnet.Session) *Session {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return self.sesByRaw[raw]
+}
+
+// newSession 创建逻辑会话
+func (self *Hooker) newSession(raw cellnet.Session, token string, server bool) *Session {
+	return &Session{
+		id:        raw.ID(),
+		token:     token,
+		server:    server,
+		p:         raw.Peer(),
+		maxReplay: self.opt.MaxReplay,
+		ackEvery:  self.opt.AckEvery,
+		ackDelay:  self.opt.AckDelay,
+	}
+}
+
+// bindManager 在 Peer 的会话管理器中使用逻辑会话替代物理会话
+func bindManager(ses *Session, raw cellnet.Session) {
+	if mgr, ok := ses.p.(peer.SessionManager); ok {
+		mgr.Remove(raw)
+		mgr.Store(ses)
+	}
+}
+
+// unbindManager 从 Peer 的会话管理器中移除逻辑会话，并退出所有分组
+func unbindManager(ses *Session) {
+	if mgr, ok := ses.p.(peer.SessionManager); ok {
+		mgr.Remove(ses)
+	}
+}
+
+// onHandshakeREQ 服务器端处理握手请求
+// 令牌有效且可以恢复时，将逻辑会话绑定到新的物理会话，否则创建新的逻辑会话
+func (self *Hooker) onHandshakeREQ(raw cellnet.Session, msg *HandshakeREQ) cellnet.Event {
+
+	self.guard.Lock()
+
+	// 同一物理连接上重新握手，旧的逻辑会话进入宽限期
+	if prev, ok := self.sesByRaw[raw]; ok {
+		delete(self.sesByRaw, raw)
+		if prev.detach(raw) {
+			self.startGrace(prev, cellnet.CloseReason_IO)
+		}
+	}
+
+	var (
+		ses     *Session
+		resumed bool
+		lost    *Session
+	)
+
+	if msg.Token != "" {
+		ses = self.sesByToken[msg.Token]
+	}
+
+	if ses != nil {
+		prevRaw := ses.Physical()
+
+		if ses.attach(raw, msg.RecvSeq, &HandshakeACK{Token: ses.token, Resumed: true}) {
+			resumed = true
+
+			// 旧连接还未检测到断开，直接关闭
+			if prevRaw != nil {
+				delete(self.sesByRaw, prevRaw)
+				prevRaw.Close()
+			}
+		} else {
+			// 对方缺少的消息已经丢弃，无法恢复
+			delete(self.sesByToken, ses.token)
+			lost = ses
+			ses = nil
+		}
+	}
+
+	if ses == nil {
+		ses = self.newSession(raw, newToken(), true)
+		self.sesByToken[ses.token] = ses
+		ses.attach(raw, 0, &HandshakeACK{Token: ses.token})
+	}
+
+	self.sesByRaw[raw] = ses
+
+	self.guard.Unlock()
+
+	bindManager(ses, raw)
+
+	if lost != nil {
+		if prevRaw := lost.Physical(); prevRaw != nil {
+			prevRaw.Close()
+		}
+
+		unbindManager(lost)
+		postEvent(lost, &cellnet.SessionClosed{})
+	}
+
+	if resumed {
+		return &cellnet.RecvMsgEvent{Ses: ses, Msg: &SessionResumed{}}
+	}
+
+	return &cellnet.RecvMsgEvent{Ses: ses, Msg: &cellnet.SessionAccepted{}}
+}
+
+// onConnected 客户端连接成功后发起握手
+// 有可恢复的逻辑会话时，携带恢复令牌和已收到的序列号
+func (self *Hooker) onConnected(raw cellnet.Session) {
+
+	self.guard.Lock()
+	ses := self.sesByPeer[raw.Peer()]
+	self.guard.Unlock()
+
+	var req HandshakeREQ
+	if ses != nil && !ses.IsClosing() {
+		req.Token = ses.token
+		req.RecvSeq = ses.recvSeqNow()
+	}
+
+	raw.Send(&req)
+}
+
+// onHandshakeACK 客户端处理握手回应
+func (self *Hooker) onHandshakeACK(raw cellnet.Session, msg *HandshakeACK) cellnet.Event {
+
+	self.guard.Lock()
+
+	ses := self.sesByPeer[raw.Peer()]
+
+	if msg.Resumed && ses != nil && ses.token == msg.Token {
+
+		if ses.attach(raw, msg.RecvSeq, nil) {
+			self.sesByRaw[raw] = ses
+			self.guard.Unlock()
+
+			bindManager(ses, raw)
+			return &cellnet.RecvMsgEvent{Ses: ses, Msg: &SessionResumed{}}
+		}
+
+		// 服务器缺少的消息已经丢弃，放弃旧的逻辑会话，重新握手
+		delete(self.sesByPeer, raw.Peer())
+		self.guard.Unlock()
+
+		raw.Send(&HandshakeREQ{})
+		return nil
+	}
+
+	ses = self.newSession(raw, msg.Token, false)
+	ses.attach(raw, 0, nil)
+	self.sesByPeer[raw.Peer()] = ses
+	self.sesByRaw[raw] = ses
+
+	self.guard.Unlock()
+
+	bindManager(ses, raw)
+
+	return &cellnet.RecvMsgEvent{Ses: ses, Msg: &cellnet.SessionConnected{}}
+}
+
+// onClosed 处理物理连接断开
+func (self *Hooker) onClosed(raw cellnet.Session, msg *cellnet.SessionClosed) cellnet.Event {
+
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	ses, ok := self.sesByRaw[raw]
+	if !ok {
+		// 未完成握手，或已经被新连接替代
+		return nil
+	}
+
+	delete(self.sesByRaw, raw)
+
+	if !ses.detach(raw) {
+		return nil
+	}
+
+	if ses.IsClosing() {
+		msg.Reason = cellnet.CloseReason_Manual
+
+		if ses.server {
+			delete(self.sesByToken, ses.token)
+		} else {
+			delete(self.sesByPeer, ses.p)
+		}
+
+		unbindManager(ses)
+		return &cellnet.RecvMsgEvent{Ses: ses, Msg: msg}
+	}
+
+	// 客户端立即通知断开，保留逻辑会话等待重连
+	if !ses.server {
+		unbindManager(ses)
+		return &cellnet.RecvMsgEvent{Ses: ses, Msg: msg}
+	}
+
+	if self.opt.GracePeriod <= 0 {
+		delete(self.sesByToken, ses.token)
+		unbindManager(ses)
+		return &cellnet.RecvMsgEvent{Ses: ses, Msg: msg}
+	}
+
+	self.startGrace(ses, msg.Reason)
+
+	return nil
+}
+
+// startGrace 开始服务器端逻辑会话的宽限期
+// 宽限期内没有恢复时，移除逻辑会话并触发 cellnet.SessionClosed 事件
+// 必须在持有 guard 时调用
+func (self *Hooker) startGrace(ses *Session, reason cellnet.CloseReason) {
+	timer := time.AfterFunc(self.opt.GracePeriod, func() {
+
+		self.guard.Lock()
+
+		// 已经恢复或已经移除
+		if ses.Physical() != nil || self.sesByToken[ses.token] != ses {
+			self.guard.Unlock()
+			return
+		}
+
+		delete(self.sesByToken, ses.token)
+		self.guard.Unlock()
+
+		ses.expire()
+		unbindManager(ses)
+		postEvent(ses, &cellnet.SessionClosed{Reason: reason})
+	})
+
+	ses.guard.Lock()
+	ses.graceTimer = timer
+	ses.guard.Unlock()
+}
+
+// postEvent 将逻辑会话上的事件重新投递到 Peer 的处理流程
+func postEvent(ses *Session, msg interface{}) {
+	if poster, ok := ses.Peer().(peer.MessagePoster); ok {
+		poster.ProcEvent(&cellnet.RecvMsgEvent{Ses: ses, Msg: msg})
+	}
+}
+
+// newToken 生成随机的恢复令牌
+func newToken() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// NewHooker 创建可靠会话的事件钩子
+// args: 绑定处理器时传入的额外参数，其中的 *Option 用于配置，未设置的字段使用默认值
+// 每个 Peer 需要使用独立的 Hooker
+func NewHooker(args ...interface{}) *Hooker {
+	self := &Hooker{
+		opt: Option{
+			GracePeriod: time.Second * 30,
+			MaxReplay:   1024,
+			AckEvery:    16,
+			AckDelay:    time.Millisecond * 200,
+		},
+		sesByRaw:   make(map[cellnet.Session]*Session),
+		sesByToken: make(map[string]*Session),
+		sesByPeer:  make(map[cellnet.Peer]*Session),
+	}
+
+	for _, arg := range args {
+		if opt, ok := arg.(*Option); ok {
+			if opt.GracePeriod != 0 {
+				self.opt.GracePeriod = opt.GracePeriod
+			}
+
+			if opt.MaxReplay > 0 {
+				self.opt.MaxReplay = opt.MaxReplay
+			}
+
+			if opt.AckEvery > 0 {
+				self.opt.AckEvery = opt.AckEvery
+			}
+
+			if opt.AckDelay != 0 {
+				self.opt.AckDelay = opt.AckDelay
+			}
+		}
+	}
+
+	return self
+}
@@ -0,0 +1,44 @@
+package reliable
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+	_ "github.com/bobwong89757/cellnet/codec/binary"
+	"github.com/bobwong89757/cellnet/util"
+)
+
+// Message 解码并返回 SeqMsg 携带的用户消息（实现 msglog.PacketMessagePeeker 接口）
+// 用于在消息日志中显示实际的用户消息
+// 解码失败时返回 nil
+func (self *SeqMsg) Message() interface{} {
+	msg, _, err := codec.DecodeMessage(int(self.MsgID), self.Data)
+	if err != nil {
+		return nil
+	}
+
+	return msg
+}
+
+// SessionResumed 表示逻辑会话恢复的事件
+// 连接断开后在宽限期内重新连接，并成功恢复原有的逻辑会话时触发
+// 事件的 Session 为原有的逻辑会话，断开期间发送的消息会在此事件前重新发送
+type SessionResumed struct {
+}
+
+// String 方法实现 fmt.Stringer 接口，用于格式化输出
+func (self *SessionResumed) String() string { return fmt.Sprintf("%+v", *self) }
+
+// SystemMessage 标记为系统消息
+func (self *SessionResumed) SystemMessage() {}
+
+func init() {
+	// 注册 SessionResumed 消息，以便 MessageDispatcher 按名称注册处理函数
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("binary"),
+		Type:  reflect.TypeOf((*SessionResumed)(nil)).Elem(),
+		ID:    int(util.StringHash("reliable.SessionResumed")),
+	})
+}
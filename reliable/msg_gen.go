@@ -0,0 +1,206 @@
+// Generated by github.com/bobwong89757/protoplus
+// DO NOT EDIT!
+package reliable
+
+import (
+	"github.com/bobwong89757/protoplus/proto"
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+	_ "github.com/bobwong89757/cellnet/codec/protoplus"
+	"reflect"
+	"unsafe"
+)
+
+var (
+	_ *proto.Buffer
+	_ codec.CodecRecycler
+	_ cellnet.Session
+	_ reflect.Type
+	_ unsafe.Pointer
+)
+
+type HandshakeREQ struct {
+	Token   string
+	RecvSeq uint64
+}
+
+func (self *HandshakeREQ) String() string { return proto.CompactTextString(self) }
+
+func (self *HandshakeREQ) Size() (ret int) {
+
+	ret += proto.SizeString(0, self.Token)
+
+	ret += proto.SizeUInt64(1, self.RecvSeq)
+
+	return
+}
+
+func (self *HandshakeREQ) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalString(buffer, 0, self.Token)
+
+	proto.MarshalUInt64(buffer, 1, self.RecvSeq)
+
+	return nil
+}
+
+func (self *HandshakeREQ) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalString(buffer, wt, &self.Token)
+	case 1:
+		return proto.UnmarshalUInt64(buffer, wt, &self.RecvSeq)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+type HandshakeACK struct {
+	Token   string
+	RecvSeq uint64
+	Resumed bool
+}
+
+func (self *HandshakeACK) String() string { return proto.CompactTextString(self) }
+
+func (self *HandshakeACK) Size() (ret int) {
+
+	ret += proto.SizeString(0, self.Token)
+
+	ret += proto.SizeUInt64(1, self.RecvSeq)
+
+	ret += proto.SizeBool(2, self.Resumed)
+
+	return
+}
+
+func (self *HandshakeACK) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalString(buffer, 0, self.Token)
+
+	proto.MarshalUInt64(buffer, 1, self.RecvSeq)
+
+	proto.MarshalBool(buffer, 2, self.Resumed)
+
+	return nil
+}
+
+func (self *HandshakeACK) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalString(buffer, wt, &self.Token)
+	case 1:
+		return proto.UnmarshalUInt64(buffer, wt, &self.RecvSeq)
+	case 2:
+		return proto.UnmarshalBool(buffer, wt, &self.Resumed)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+type SeqMsg struct {
+	Seq   uint64
+	Ack   uint64
+	MsgID uint32
+	Data  []byte
+}
+
+func (self *SeqMsg) String() string { return proto.CompactTextString(self) }
+
+func (self *SeqMsg) Size() (ret int) {
+
+	ret += proto.SizeUInt64(0, self.Seq)
+
+	ret += proto.SizeUInt64(1, self.Ack)
+
+	ret += proto.SizeUInt32(2, self.MsgID)
+
+	ret += proto.SizeBytes(3, self.Data)
+
+	return
+}
+
+func (self *SeqMsg) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalUInt64(buffer, 0, self.Seq)
+
+	proto.MarshalUInt64(buffer, 1, self.Ack)
+
+	proto.MarshalUInt32(buffer, 2, self.MsgID)
+
+	proto.MarshalBytes(buffer, 3, self.Data)
+
+	return nil
+}
+
+func (self *SeqMsg) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalUInt64(buffer, wt, &self.Seq)
+	case 1:
+		return proto.UnmarshalUInt64(buffer, wt, &self.Ack)
+	case 2:
+		return proto.UnmarshalUInt32(buffer, wt, &self.MsgID)
+	case 3:
+		return proto.UnmarshalBytes(buffer, wt, &self.Data)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+type SeqAck struct {
+	Ack uint64
+}
+
+func (self *SeqAck) String() string { return proto.CompactTextString(self) }
+
+func (self *SeqAck) Size() (ret int) {
+
+	ret += proto.SizeUInt64(0, self.Ack)
+
+	return
+}
+
+func (self *SeqAck) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalUInt64(buffer, 0, self.Ack)
+
+	return nil
+}
+
+func (self *SeqAck) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalUInt64(buffer, wt, &self.Ack)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+func init() {
+
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*HandshakeREQ)(nil)).Elem(),
+		ID:    22735,
+	})
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*HandshakeACK)(nil)).Elem(),
+		ID:    50102,
+	})
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*SeqMsg)(nil)).Elem(),
+		ID:    14800,
+	})
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*SeqAck)(nil)).Elem(),
+		ID:    30904,
+	})
+}
@@ -0,0 +1,344 @@
+package reliable
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+	"github.com/bobwong89757/cellnet/log"
+	"github.com/bobwong89757/cellnet/peer"
+)
+
+// Session 可靠逻辑会话
+// 包装底层的物理连接会话，为每个消息分配序列号并缓存未确认的消息
+// 物理连接断开并重新连接后，逻辑会话保持不变，未确认的消息会重新发送
+// 实现 cellnet.Session 和 cellnet.ContextSet 接口，可以像普通会话一样使用
+type Session struct {
+	peer.CoreContextSet
+
+	// id 逻辑会话 ID，取第一次绑定的物理会话 ID
+	id int64
+
+	// token 恢复令牌，重连时用于找回逻辑会话
+	token string
+
+	// server 是否为服务器端（Acceptor）的逻辑会话
+	server bool
+
+	// p 所属的 Peer
+	p cellnet.Peer
+
+	// maxReplay 重发缓冲的最大消息数量
+	maxReplay int
+
+	// ackEvery 收到多少个消息后发送一次确认
+	ackEvery int
+
+	// ackDelay 收到消息后最迟多久发送确认，小于等于 0 时只按 ackEvery 确认
+	ackDelay time.Duration
+
+	// guard 保护以下字段的互斥锁
+	guard sync.Mutex
+
+	// raw 当前绑定的物理会话，断开期间为 nil
+	raw cellnet.Session
+
+	// sendSeq 最后一个发送消息的序列号
+	sendSeq uint64
+
+	// recvSeq 最后一个收到消息的序列号
+	recvSeq uint64
+
+	// unacked 已收到但还未确认的消息数量
+	unacked int
+
+	// replay 已发送但对方还未确认的消息，按序列号递增排列
+	replay []*SeqMsg
+
+	// closing 是否已调用 Close
+	closing bool
+
+	// graceTimer 服务器端宽限期计时器
+	graceTimer *time.Timer
+
+	// ackTimer 延迟确认计时器，有未确认的消息时启动
+	ackTimer *time.Timer
+}
+
+// ID 返回逻辑会话 ID
+// 物理连接变化时保持不变
+func (self *Session) ID() int64 {
+	return self.id
+}
+
+// Token 返回恢复令牌
+func (self *Session) Token() string {
+	return self.token
+}
+
+// Peer 返回会话所属的 Peer
+func (self *Session) Peer() cellnet.Peer {
+	return self.p
+}
+
+// Raw 返回当前物理会话的原始连接
+// 断开期间返回 nil
+func (self *Session) Raw() interface{} {
+	if raw := self.Physical(); raw != nil {
+		return raw.Raw()
+	}
+
+	return nil
+}
+
+// Unacked 返回已发送但对方还未确认的消息数量
+// 这些消息保留在重发缓冲中，恢复会话时重新发送
+func (self *Session) Unacked() int {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return len(self.replay)
+}
+
+// Physical 返回当前绑定的物理会话
+// 断开期间返回 nil
+func (self *Session) Physical() cellnet.Session {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return self.raw
+}
+
+// Send 发送消息
+// msg: 要发送的消息对象，也可以是 *cellnet.RawPacket
+// 消息会被分配序列号并放入重发缓冲，断开期间发送的消息会在恢复后发送
+func (self *Session) Send(msg interface{}) {
+	if msg == nil {
+		return
+	}
+
+	var (
+		msgID int
+		data  []byte
+	)
+
+	if raw, ok := msg.(*cellnet.RawPacket); ok {
+		msgID, data = raw.MsgID, raw.MsgData
+	} else {
+		var meta *cellnet.MessageMeta
+		var err error
+		data, meta, err = codec.EncodeMessage(msg, nil)
+		if err != nil {
+			log.GetLog().Errorf("reliable send message encode error: %s", err)
+			return
+		}
+
+		msgID = meta.ID
+	}
+
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if self.closing {
+		return
+	}
+
+	self.sendSeq++
+
+	seqMsg := &SeqMsg{
+		Seq:   self.sendSeq,
+		MsgID: uint32(msgID),
+		Data:  data,
+	}
+
+	// 缓冲已满时丢弃最旧的消息，此后对方缺少该消息时将无法恢复会话
+	if len(self.replay) >= self.maxReplay {
+		self.replay[0] = nil
+		self.replay = self.replay[1:]
+	}
+
+	self.replay = append(self.replay, seqMsg)
+
+	// 在锁内发送，保证消息按序列号顺序进入发送队列
+	if self.raw != nil {
+		self.raw.Send(self.withAck(seqMsg))
+	}
+}
+
+// withAck 返回捎带当前确认序列号的消息副本
+// 必须在持有锁时调用
+func (self *Session) withAck(seqMsg *SeqMsg) *SeqMsg {
+	self.unacked = 0
+
+	// 已经捎带确认，不再需要延迟确认
+	self.stopAckTimer()
+
+	return &SeqMsg{
+		Seq:   seqMsg.Seq,
+		Ack:   self.recvSeq,
+		MsgID: seqMsg.MsgID,
+		Data:  seqMsg.Data,
+	}
+}
+
+// Close 关闭逻辑会话
+// 关闭当前的物理连接，且不再保留逻辑会话用于恢复
+func (self *Session) Close() {
+	self.guard.Lock()
+	self.closing = true
+	self.stopAckTimer()
+	raw := self.raw
+	self.guard.Unlock()
+
+	if raw != nil {
+		raw.Close()
+	}
+}
+
+// IsClosing 检查是否已调用 Close
+func (self *Session) IsClosing() bool {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return self.closing
+}
+
+// recvSeqNow 返回最后一个收到消息的序列号
+func (self *Session) recvSeqNow() uint64 {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return self.recvSeq
+}
+
+// onAck 处理对方的确认，移除已确认的消息
+// 必须在持有锁时调用
+func (self *Session) onAck(ack uint64) {
+	n := 0
+	for n < len(self.replay) && self.replay[n].Seq <= ack {
+		self.replay[n] = nil
+		n++
+	}
+
+	self.replay = self.replay[n:]
+}
+
+// ack 处理独立的确认消息
+func (self *Session) ack(ack uint64) {
+	self.guard.Lock()
+	self.onAck(ack)
+	self.guard.Unlock()
+}
+
+// recv 处理收到的序列号消息
+// 返回 false 表示重复的消息，需要丢弃
+func (self *Session) recv(msg *SeqMsg) bool {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.onAck(msg.Ack)
+
+	// 恢复后对方重发的已收到消息
+	if msg.Seq <= self.recvSeq {
+		return false
+	}
+
+	self.recvSeq = msg.Seq
+	self.unacked++
+
+	if self.unacked >= self.ackEvery && self.raw != nil {
+		self.unacked = 0
+		self.stopAckTimer()
+		self.raw.Send(&SeqAck{Ack: self.recvSeq})
+	} else if self.ackDelay > 0 && self.ackTimer == nil {
+		// 消息较少时数量达不到 ackEvery，延迟一段时间后确认
+		self.ackTimer = time.AfterFunc(self.ackDelay, self.flushAck)
+	}
+
+	return true
+}
+
+// stopAckTimer 停止延迟确认计时器
+// 必须在持有锁时调用
+func (self *Session) stopAckTimer() {
+	if self.ackTimer != nil {
+		self.ackTimer.Stop()
+		self.ackTimer = nil
+	}
+}
+
+// flushAck 延迟确认到期，发送还未确认的消息的确认
+// 期间已经捎带确认或按数量确认时不再发送
+func (self *Session) flushAck() {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.ackTimer = nil
+
+	if self.unacked > 0 && self.raw != nil {
+		self.unacked = 0
+		self.raw.Send(&SeqAck{Ack: self.recvSeq})
+	}
+}
+
+// attach 绑定新的物理会话
+// raw: 新的物理会话
+// peerRecvSeq: 对方已经收到的最大消息序列号
+// ack: 服务器端需要在重发消息前发送的握手回应，客户端为 nil
+// 对方缺少的消息已经不在重发缓冲中时无法恢复，返回 false
+func (self *Session) attach(raw cellnet.Session, peerRecvSeq uint64, ack *HandshakeACK) bool {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	// 重发缓冲中最早的消息之前的序列号
+	oldest := self.sendSeq - uint64(len(self.replay))
+	if peerRecvSeq < oldest || peerRecvSeq > self.sendSeq {
+		return false
+	}
+
+	if self.graceTimer != nil {
+		self.graceTimer.Stop()
+		self.graceTimer = nil
+	}
+
+	self.onAck(peerRecvSeq)
+	self.raw = raw
+
+	if ack != nil {
+		ack.RecvSeq = self.recvSeq
+		raw.Send(ack)
+	}
+
+	// 重发对方未收到的消息
+	for _, seqMsg := range self.replay {
+		raw.Send(self.withAck(seqMsg))
+	}
+
+	return true
+}
+
+// detach 解除与物理会话的绑定
+// raw: 已断开的物理会话
+// 返回 false 表示逻辑会话已经绑定到其他物理会话
+func (self *Session) detach(raw cellnet.Session) bool {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if self.raw != raw {
+		return false
+	}
+
+	self.raw = nil
+	self.stopAckTimer()
+	return true
+}
+
+// expire 服务器端逻辑会话宽限期到期，释放计时器
+func (self *Session) expire() {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.graceTimer = nil
+	self.stopAckTimer()
+}
@@ -0,0 +1,64 @@
+package reliable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+)
+
+type ackTestSession struct {
+	peer.CoreSessionIdentify
+	sent []interface{}
+}
+
+func (self *ackTestSession) Raw() interface{}     { return nil }
+func (self *ackTestSession) Peer() cellnet.Peer   { return nil }
+func (self *ackTestSession) Send(msg interface{}) { self.sent = append(self.sent, msg) }
+func (self *ackTestSession) Close()               {}
+
+func TestSessionAckTimer(t *testing.T) {
+
+	raw := new(ackTestSession)
+
+	ses := &Session{
+		maxReplay: 16,
+		ackEvery:  16,
+		ackDelay:  time.Hour,
+		raw:       raw,
+	}
+
+	// 收到消息后启动延迟确认，捎带确认后停止
+	ses.recv(&SeqMsg{Seq: 1})
+	if ses.ackTimer == nil {
+		t.Fatal("ack timer not started")
+	}
+
+	ses.Send(&cellnet.RawPacket{MsgID: 1})
+	if ses.ackTimer != nil {
+		t.Error("ack timer not stopped after piggyback ack")
+	}
+
+	// 断开时停止
+	ses.recv(&SeqMsg{Seq: 2})
+	ses.detach(raw)
+	if ses.ackTimer != nil {
+		t.Error("ack timer not stopped after detach")
+	}
+
+	// 关闭时停止
+	ses.attach(raw, ses.sendSeq, nil)
+	ses.recv(&SeqMsg{Seq: 3})
+	ses.Close()
+	if ses.ackTimer != nil {
+		t.Error("ack timer not stopped after close")
+	}
+
+	// 宽限期到期时停止
+	ses.ackTimer = time.AfterFunc(time.Hour, ses.flushAck)
+	ses.expire()
+	if ses.ackTimer != nil {
+		t.Error("ack timer not stopped after expire")
+	}
+}
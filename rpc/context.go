@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+// sessionContext Session 生命周期的上下文
+// Session 关闭时取消，服务器端请求的上下文都派生自此上下文
+type sessionContext struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// sessionContextKey 在 Session 上保存 sessionContext 的键
+var sessionContextKey = cellnet.NewContextKey[*sessionContext]("rpc.sessionContext")
+
+// sessionContextOf 获取 Session 生命周期的上下文
+// ses: 会话对象
+// 第一次获取时创建，Session 不支持上下文数据时返回 context.Background()
+func sessionContextOf(ses cellnet.Session) context.Context {
+	cs, ok := ses.(cellnet.ContextSet)
+	if !ok {
+		return context.Background()
+	}
+
+	return sessionContextKey.GetOrInit(cs, newSessionContext).ctx
+}
+
+// newSessionContext 创建 Session 生命周期的上下文
+func newSessionContext() *sessionContext {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &sessionContext{ctx: ctx, cancel: cancel}
+}
+
+// cancelSessionContext 取消 Session 生命周期的上下文
+// ses: 已关闭的会话对象
+// 取消后的上下文仍保留在 Session 上，关闭后才开始处理的请求也能获得已取消的上下文
+func cancelSessionContext(ses cellnet.Session) {
+	cs, ok := ses.(cellnet.ContextSet)
+	if !ok {
+		return
+	}
+
+	sessionContextKey.GetOrInit(cs, newSessionContext).cancel()
+}
+
+// resetSessionContext 移除 Session 上已取消的上下文
+// ses: 重新连接的会话对象
+// 连接器重连后复用同一个 Session，需要重新创建上下文
+func resetSessionContext(ses cellnet.Session) {
+	if cs, ok := ses.(cellnet.ContextSet); ok {
+		sessionContextKey.Delete(cs)
+	}
+}
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+// DefaultDedupTTL 去重缓存默认的保留时间
+const DefaultDedupTTL = time.Minute
+
+// dedupEntry 一个幂等键的处理结果
+type dedupEntry struct {
+	// done 处理完成时关闭
+	done chan struct{}
+
+	// ack 处理函数返回的响应消息
+	ack interface{}
+
+	// err 处理函数返回的错误
+	err error
+}
+
+// dedupExpire 去重缓存的过期记录，按处理完成的顺序排列
+type dedupExpire struct {
+	key    string
+	expire time.Time
+}
+
+// dedupCache 短时间保留处理结果的去重缓存
+type dedupCache struct {
+	ttl time.Duration
+
+	// guard 保护以下字段
+	guard sync.Mutex
+
+	// entryByKey 按请求消息名称和幂等键存储处理结果
+	entryByKey map[string]*dedupEntry
+
+	// expires 已完成的处理结果的过期时间
+	expires []dedupExpire
+}
+
+// acquire 获取幂等键的处理结果
+// 返回处理结果和是否第一次出现，第一次出现时由调用方执行处理并调用 complete
+func (self *dedupCache) acquire(key string) (*dedupEntry, bool) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	self.purge(time.Now())
+
+	if entry, ok := self.entryByKey[key]; ok {
+		return entry, false
+	}
+
+	entry := &dedupEntry{done: make(chan struct{})}
+	self.entryByKey[key] = entry
+
+	return entry, true
+}
+
+// complete 保存处理结果，唤醒等待同一个幂等键的请求
+func (self *dedupCache) complete(key string, entry *dedupEntry, ack interface{}, err error) {
+	entry.ack, entry.err = ack, err
+	close(entry.done)
+
+	self.guard.Lock()
+	self.expires = append(self.expires, dedupExpire{key: key, expire: time.Now().Add(self.ttl)})
+	self.guard.Unlock()
+}
+
+// purge 移除已过期的处理结果
+// 调用时需持有 guard
+func (self *dedupCache) purge(now time.Time) {
+	var n int
+	for n < len(self.expires) && !now.Before(self.expires[n].expire) {
+		delete(self.entryByKey, self.expires[n].key)
+		n++
+	}
+
+	if n > 0 {
+		self.expires = append(self.expires[:0], self.expires[n:]...)
+	}
+}
+
+// DedupInterceptor 按幂等键去重的拦截器
+// ttl: 处理结果的保留时间，小于等于 0 时使用 DefaultDedupTTL
+// 调用方为请求类型设置 RetryPolicy 后，同一次调用的重试携带相同的幂等键
+// 幂等键第一次出现时执行处理函数并缓存结果，保留时间内重复的请求直接回应缓存的结果，不会再次执行
+// 重复的请求在第一次处理完成前到达时（多个事件队列共用服务），等待第一次处理完成
+// 没有幂等键的请求不去重；处理函数自行回应（返回 nil, nil）的请求，重复时不会得到回应
+// 缓存只在当前进程内有效，重试的请求被发往其他副本时无法去重
+func DedupInterceptor(ttl time.Duration) Interceptor {
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+
+	cache := &dedupCache{
+		ttl:        ttl,
+		entryByKey: map[string]*dedupEntry{},
+	}
+
+	return func(ev *RecvMsgEvent, next HandlerFunc) (ack interface{}, err error) {
+		if ev.IdempotencyKey() == "" {
+			return next(ev)
+		}
+
+		key := cellnet.MessageToName(ev.Msg) + ":" + ev.IdempotencyKey()
+
+		entry, first := cache.acquire(key)
+		if !first {
+			<-entry.done
+			return entry.ack, entry.err
+		}
+
+		completed := false
+
+		// 处理函数 panic 时，重复的请求回应内部错误
+		defer func() {
+			if !completed {
+				cache.complete(key, entry, nil, &RemoteError{Code: ErrCodeInternal, Message: "request failed"})
+			}
+		}()
+
+		ack, err = next(ev)
+
+		cache.complete(key, entry, ack, err)
+		completed = true
+
+		return ack, err
+	}
+}
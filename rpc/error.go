@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+	"github.com/bobwong89757/cellnet/log"
+)
+
+// ErrCodeUnknown 未指定错误码时使用的错误码
+// RemoteCallACK 的 ErrCode 为 0 表示正常回应，因此错误码不能为 0
+const ErrCodeUnknown int32 = -1
+
+// RemoteError 服务器端回应的 RPC 错误
+// 服务器通过 ReplyError 回应错误时，调用方的 Call、CallSync、CallType 等回调会收到此错误
+type RemoteError struct {
+	// Code 错误码，不为 0
+	Code int32
+
+	// Message 错误描述
+	Message string
+
+	// Detail 错误的详细信息消息，没有时为 nil
+	Detail interface{}
+}
+
+// Error 实现 error 接口
+func (self *RemoteError) Error() string {
+	return fmt.Sprintf("rpc remote error(%d): %s", self.Code, self.Message)
+}
+
+// newRemoteError 从错误回应创建 RemoteError
+// ack: 带有错误码的 RemoteCallACK
+// 详细信息消息解码失败时忽略
+func newRemoteError(ack *RemoteCallACK) *RemoteError {
+	self := &RemoteError{
+		Code:    ack.ErrCode,
+		Message: ack.ErrMsg,
+	}
+
+	if ack.DetailMsgID != 0 {
+		detail, _, err := codec.DecodeMessage(int(ack.DetailMsgID), ack.DetailData)
+		if err != nil {
+			log.GetLog().Errorf("rpc remote error detail decode error: %s", err)
+		} else {
+			self.Detail = detail
+		}
+	}
+
+	return self
+}
+
+// ReplyError 回应 RPC 错误
+// ev: 服务器端收到请求的事件，必须是 *RecvMsgEvent
+// code: 错误码，为 0 时使用 ErrCodeUnknown
+// message: 错误描述
+// detail: 错误的详细信息消息，可以为 nil
+func ReplyError(ev cellnet.Event, code int32, message string, detail interface{}) {
+	rpcEv, ok := ev.(*RecvMsgEvent)
+	if !ok {
+		log.GetLog().Errorf("rpc reply error require rpc.RecvMsgEvent, got %T", ev)
+		return
+	}
+
+	ev.Session().Send(newErrorACK(rpcEv.callid, code, message, detail))
+}
+
+// newErrorACK 创建错误回应
+// callid: 请求的调用 ID
+// code: 错误码，为 0 时使用 ErrCodeUnknown
+// message: 错误描述
+// detail: 错误的详细信息消息，可以为 nil
+func newErrorACK(callid int64, code int32, message string, detail interface{}) *RemoteCallACK {
+	if code == 0 {
+		code = ErrCodeUnknown
+	}
+
+	ack := &RemoteCallACK{
+		CallID:  callid,
+		ErrCode: code,
+		ErrMsg:  message,
+	}
+
+	if detail != nil {
+		data, meta, err := codec.EncodeMessage(detail, nil)
+		if err != nil {
+			log.GetLog().Errorf("rpc remote error detail encode error: %s", err)
+		} else {
+			ack.DetailMsgID = uint32(meta.ID)
+			ack.DetailData = data
+		}
+	}
+
+	return ack
+}
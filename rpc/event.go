@@ -1,6 +1,10 @@
 package rpc
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/codec"
 	"github.com/bobwong89757/cellnet/log"
@@ -19,6 +23,24 @@ type RecvMsgEvent struct {
 	// callid 调用 ID
 	// 用于关联请求和响应
 	callid int64
+
+	// deadline 调用方的截止时间，由收到请求的时间和调用方剩余的超时时间计算，零值表示没有截止时间
+	deadline time.Time
+
+	// ctxOnce 保证请求上下文只创建一次
+	ctxOnce sync.Once
+
+	// ctx 请求上下文，第一次调用 Context 时创建
+	ctx context.Context
+
+	// cancel 取消请求上下文，回复时调用
+	cancel context.CancelFunc
+
+	// stream 流式请求的服务器端流，普通请求为 nil
+	stream *ServerStream
+
+	// idemKey 调用方设置的幂等键，没有时为空
+	idemKey string
 }
 
 // Session 获取会话对象
@@ -41,6 +63,42 @@ func (self *RecvMsgEvent) Queue() cellnet.EventQueue {
 	}).Queue()
 }
 
+// Deadline 获取调用方的截止时间
+// 返回截止时间和是否设置了截止时间
+// 截止时间以本机收到请求的时间加上调用方剩余的超时时间计算，不受双方时钟偏差的影响
+func (self *RecvMsgEvent) Deadline() (time.Time, bool) {
+	return self.deadline, !self.deadline.IsZero()
+}
+
+// Context 获取请求上下文
+// 超过调用方的截止时间或 Session 关闭时，上下文被取消
+// 处理函数可以据此跳过已经没有意义的工作
+// 回复后返回的上下文已取消，不会返回 nil
+func (self *RecvMsgEvent) Context() context.Context {
+	self.ctxOnce.Do(func() {
+		if self.deadline.IsZero() {
+			self.ctx, self.cancel = context.WithCancel(sessionContextOf(self.ses))
+		} else {
+			self.ctx, self.cancel = context.WithDeadline(sessionContextOf(self.ses), self.deadline)
+		}
+	})
+
+	return self.ctx
+}
+
+// IdempotencyKey 获取调用方设置的幂等键
+// 请求类型设置了重试策略时，同一次调用的多次重试使用相同的幂等键，没有时返回空字符串
+func (self *RecvMsgEvent) IdempotencyKey() string {
+	return self.idemKey
+}
+
+// Stream 获取流式请求的服务器端流
+// 客户端通过 CallStream 发起请求时返回用于发送多个数据帧的流，普通请求返回 nil
+// 使用流时不要再调用 Reply，通过 ServerStream.End 或 ServerStream.EndError 结束请求
+func (self *RecvMsgEvent) Stream() *ServerStream {
+	return self.stream
+}
+
 // Reply 回复消息
 // msg: 要回复的消息对象
 // 将消息编码后通过 RemoteCallACK 发送，使用调用 ID 关联请求和响应
@@ -54,6 +112,13 @@ func (self *RecvMsgEvent) Reply(msg interface{}) {
 		return
 	}
 
+	self.release()
+
+	// 流式请求以普通回应结束，客户端收到一个数据帧后结束
+	if self.stream != nil {
+		self.stream.detach()
+	}
+
 	// 发送 RPC 响应，使用调用 ID 关联
 	self.ses.Send(&RemoteCallACK{
 		MsgID:  uint32(meta.ID),
@@ -61,3 +126,37 @@ func (self *RecvMsgEvent) Reply(msg interface{}) {
 		CallID: self.callid,
 	})
 }
+
+// ReplyError 回应 RPC 错误
+// code: 错误码，为 0 时使用 ErrCodeUnknown
+// message: 错误描述
+// detail: 错误的详细信息消息，可以为 nil
+// 调用方会收到 *RemoteError
+func (self *RecvMsgEvent) ReplyError(code int32, message string, detail interface{}) {
+	self.release()
+
+	if self.stream != nil {
+		self.stream.detach()
+	}
+
+	ReplyError(self, code, message, detail)
+}
+
+// repliedContext 回复前没有创建请求上下文时使用的已取消的上下文
+var repliedContext = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// release 已经回复，取消请求上下文并释放计时器
+// 回复前没有创建请求上下文时不再创建，之后调用 Context 返回已取消的上下文
+func (self *RecvMsgEvent) release() {
+	self.ctxOnce.Do(func() {
+		self.ctx = repliedContext
+	})
+
+	if self.cancel != nil {
+		self.cancel()
+	}
+}
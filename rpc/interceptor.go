@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/log"
+)
+
+// RecoveryInterceptor 捕获处理函数 panic 的拦截器
+// 发生 panic 时记录日志和调用栈，并以 ErrCodeInternal 回应错误
+// 一般作为第一个拦截器，保护其后的所有拦截器和处理函数
+func RecoveryInterceptor() Interceptor {
+	return func(ev *RecvMsgEvent, next HandlerFunc) (ack interface{}, err error) {
+		defer func() {
+			if raw := recover(); raw != nil {
+				log.GetLog().Errorf("rpc service panic: %v, msg: %s\n%s", raw, cellnet.MessageToName(ev.Msg), debug.Stack())
+
+				ack, err = nil, &RemoteError{Code: ErrCodeInternal, Message: fmt.Sprint(raw)}
+			}
+		}()
+
+		return next(ev)
+	}
+}
+
+// LoggingInterceptor 记录请求处理结果和耗时的拦截器
+func LoggingInterceptor() Interceptor {
+	return func(ev *RecvMsgEvent, next HandlerFunc) (interface{}, error) {
+		begin := time.Now()
+
+		ack, err := next(ev)
+
+		if err != nil {
+			log.GetLog().Warnf("#rpc.service(%s)@%d %s failed(%v) cost: %s",
+				ev.Session().Peer().(cellnet.PeerProperty).Name(),
+				ev.Session().ID(),
+				cellnet.MessageToName(ev.Msg),
+				err,
+				time.Since(begin))
+		} else {
+			log.GetLog().Debugf("#rpc.service(%s)@%d %s ok cost: %s",
+				ev.Session().Peer().(cellnet.PeerProperty).Name(),
+				ev.Session().ID(),
+				cellnet.MessageToName(ev.Msg),
+				time.Since(begin))
+		}
+
+		return ack, err
+	}
+}
+
+// MetricsInterceptor 统计请求处理耗时和结果的拦截器
+// observer: 统计回调，参数为请求消息名称、处理耗时和处理结果错误
+func MetricsInterceptor(observer func(msgName string, cost time.Duration, err error)) Interceptor {
+	return func(ev *RecvMsgEvent, next HandlerFunc) (interface{}, error) {
+		begin := time.Now()
+
+		ack, err := next(ev)
+
+		observer(cellnet.MessageToName(ev.Msg), time.Since(begin), err)
+
+		return ack, err
+	}
+}
+
+// AuthInterceptor 认证拦截器
+// check: 认证检查函数，返回错误时拒绝请求
+// 认证失败时，*RemoteError 原样回应，其他错误以 ErrCodeUnauthenticated 回应
+func AuthInterceptor(check func(ev *RecvMsgEvent) error) Interceptor {
+	return func(ev *RecvMsgEvent, next HandlerFunc) (interface{}, error) {
+		if err := check(ev); err != nil {
+			if remoteErr, ok := err.(*RemoteError); ok {
+				return nil, remoteErr
+			}
+
+			return nil, &RemoteError{Code: ErrCodeUnauthenticated, Message: err.Error()}
+		}
+
+		return next(ev)
+	}
+}
@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrAckTypeMismatch 表示收到的响应消息类型与期望的类型不一致
+var ErrAckTypeMismatch = errors.New("rpc: Ack message type mismatch")
+
+// Invoke 执行类型安全的同步 RPC 请求
+// Req: 请求消息类型
+// Ack: 响应消息类型
+// ctx: 请求的上下文，取消或超过截止时间时请求结束，截止时间会随请求发送给服务器
+// sesOrPeer: Session 或 Peer，用于发送请求
+// req: 请求消息对象
+// 返回响应消息和错误信息，响应消息类型不是 *Ack 时返回 ErrAckTypeMismatch
+// 此方法会阻塞当前 goroutine，直到收到响应、ctx 结束或 Session 关闭
+//
+// 使用示例:
+//
+//	ack, err := rpc.Invoke[LoginREQ, LoginACK](ctx, ses, &LoginREQ{})
+func Invoke[Req, Ack any](ctx context.Context, sesOrPeer interface{}, req *Req) (*Ack, error) {
+	raw, err := CallContext(ctx, sesOrPeer, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ackAs[Ack](raw)
+}
+
+// InvokeAsync 执行类型安全的异步 RPC 请求
+// Req: 请求消息类型
+// Ack: 响应消息类型
+// ctx: 请求的上下文，取消或超过截止时间时，回调收到 ctx.Err()
+// sesOrPeer: Session 或 Peer，用于发送请求
+// req: 请求消息对象
+// callback: 响应回调函数，在 Session 对应 Peer 的事件队列中执行
+// 出错时 ack 为 nil，响应消息类型不是 *Ack 时 err 为 ErrAckTypeMismatch
+// 请求类型设置了重试策略时，与 CallContextAsync 一样自动重试
+// 此方法不会阻塞，立即返回
+func InvokeAsync[Req, Ack any](ctx context.Context, sesOrPeer interface{}, req *Req, callback func(ack *Ack, err error)) {
+	CallContextAsync(ctx, sesOrPeer, req, func(raw interface{}) {
+		if err, ok := raw.(error); ok {
+			callback(nil, err)
+			return
+		}
+
+		callback(ackAs[Ack](raw))
+	})
+}
+
+// ackAs 将响应消息转换为 *Ack
+// raw: 响应消息
+// 类型不一致时返回 ErrAckTypeMismatch
+func ackAs[Ack any](raw interface{}) (*Ack, error) {
+	if ack, ok := raw.(*Ack); ok {
+		return ack, nil
+	}
+
+	var expect *Ack
+	return nil, fmt.Errorf("%w: expect %T, got %T", ErrAckTypeMismatch, expect, raw)
+}
@@ -20,9 +20,12 @@ var (
 )
 
 type RemoteCallREQ struct {
-	MsgID  uint32
-	Data   []byte
-	CallID int64
+	MsgID   uint32
+	Data    []byte
+	CallID  int64
+	Timeout int64
+	Window  uint32
+	IdemKey string
 }
 
 func (self *RemoteCallREQ) String() string { return proto.CompactTextString(self) }
@@ -35,6 +38,12 @@ func (self *RemoteCallREQ) Size() (ret int) {
 
 	ret += proto.SizeInt64(2, self.CallID)
 
+	ret += proto.SizeInt64(3, self.Timeout)
+
+	ret += proto.SizeUInt32(4, self.Window)
+
+	ret += proto.SizeString(5, self.IdemKey)
+
 	return
 }
 
@@ -46,6 +55,12 @@ func (self *RemoteCallREQ) Marshal(buffer *proto.Buffer) error {
 
 	proto.MarshalInt64(buffer, 2, self.CallID)
 
+	proto.MarshalInt64(buffer, 3, self.Timeout)
+
+	proto.MarshalUInt32(buffer, 4, self.Window)
+
+	proto.MarshalString(buffer, 5, self.IdemKey)
+
 	return nil
 }
 
@@ -57,6 +72,12 @@ func (self *RemoteCallREQ) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt
 		return proto.UnmarshalBytes(buffer, wt, &self.Data)
 	case 2:
 		return proto.UnmarshalInt64(buffer, wt, &self.CallID)
+	case 3:
+		return proto.UnmarshalInt64(buffer, wt, &self.Timeout)
+	case 4:
+		return proto.UnmarshalUInt32(buffer, wt, &self.Window)
+	case 5:
+		return proto.UnmarshalString(buffer, wt, &self.IdemKey)
 
 	}
 
@@ -64,9 +85,13 @@ func (self *RemoteCallREQ) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt
 }
 
 type RemoteCallACK struct {
-	MsgID  uint32
-	Data   []byte
-	CallID int64
+	MsgID       uint32
+	Data        []byte
+	CallID      int64
+	ErrCode     int32
+	ErrMsg      string
+	DetailMsgID uint32
+	DetailData  []byte
 }
 
 func (self *RemoteCallACK) String() string { return proto.CompactTextString(self) }
@@ -79,6 +104,14 @@ func (self *RemoteCallACK) Size() (ret int) {
 
 	ret += proto.SizeInt64(2, self.CallID)
 
+	ret += proto.SizeInt32(3, self.ErrCode)
+
+	ret += proto.SizeString(4, self.ErrMsg)
+
+	ret += proto.SizeUInt32(5, self.DetailMsgID)
+
+	ret += proto.SizeBytes(6, self.DetailData)
+
 	return
 }
 
@@ -90,6 +123,14 @@ func (self *RemoteCallACK) Marshal(buffer *proto.Buffer) error {
 
 	proto.MarshalInt64(buffer, 2, self.CallID)
 
+	proto.MarshalInt32(buffer, 3, self.ErrCode)
+
+	proto.MarshalString(buffer, 4, self.ErrMsg)
+
+	proto.MarshalUInt32(buffer, 5, self.DetailMsgID)
+
+	proto.MarshalBytes(buffer, 6, self.DetailData)
+
 	return nil
 }
 
@@ -101,6 +142,102 @@ func (self *RemoteCallACK) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt
 		return proto.UnmarshalBytes(buffer, wt, &self.Data)
 	case 2:
 		return proto.UnmarshalInt64(buffer, wt, &self.CallID)
+	case 3:
+		return proto.UnmarshalInt32(buffer, wt, &self.ErrCode)
+	case 4:
+		return proto.UnmarshalString(buffer, wt, &self.ErrMsg)
+	case 5:
+		return proto.UnmarshalUInt32(buffer, wt, &self.DetailMsgID)
+	case 6:
+		return proto.UnmarshalBytes(buffer, wt, &self.DetailData)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+type RemoteStreamFrame struct {
+	MsgID  uint32
+	Data   []byte
+	CallID int64
+}
+
+func (self *RemoteStreamFrame) String() string { return proto.CompactTextString(self) }
+
+func (self *RemoteStreamFrame) Size() (ret int) {
+
+	ret += proto.SizeUInt32(0, self.MsgID)
+
+	ret += proto.SizeBytes(1, self.Data)
+
+	ret += proto.SizeInt64(2, self.CallID)
+
+	return
+}
+
+func (self *RemoteStreamFrame) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalUInt32(buffer, 0, self.MsgID)
+
+	proto.MarshalBytes(buffer, 1, self.Data)
+
+	proto.MarshalInt64(buffer, 2, self.CallID)
+
+	return nil
+}
+
+func (self *RemoteStreamFrame) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalUInt32(buffer, wt, &self.MsgID)
+	case 1:
+		return proto.UnmarshalBytes(buffer, wt, &self.Data)
+	case 2:
+		return proto.UnmarshalInt64(buffer, wt, &self.CallID)
+
+	}
+
+	return proto.ErrUnknownField
+}
+
+type RemoteStreamCtrl struct {
+	CallID  int64
+	Credits uint32
+	Cancel  bool
+}
+
+func (self *RemoteStreamCtrl) String() string { return proto.CompactTextString(self) }
+
+func (self *RemoteStreamCtrl) Size() (ret int) {
+
+	ret += proto.SizeInt64(0, self.CallID)
+
+	ret += proto.SizeUInt32(1, self.Credits)
+
+	ret += proto.SizeBool(2, self.Cancel)
+
+	return
+}
+
+func (self *RemoteStreamCtrl) Marshal(buffer *proto.Buffer) error {
+
+	proto.MarshalInt64(buffer, 0, self.CallID)
+
+	proto.MarshalUInt32(buffer, 1, self.Credits)
+
+	proto.MarshalBool(buffer, 2, self.Cancel)
+
+	return nil
+}
+
+func (self *RemoteStreamCtrl) Unmarshal(buffer *proto.Buffer, fieldIndex uint64, wt proto.WireType) error {
+	switch fieldIndex {
+	case 0:
+		return proto.UnmarshalInt64(buffer, wt, &self.CallID)
+	case 1:
+		return proto.UnmarshalUInt32(buffer, wt, &self.Credits)
+	case 2:
+		return proto.UnmarshalBool(buffer, wt, &self.Cancel)
 
 	}
 
@@ -119,4 +256,14 @@ func init() {
 		Type:  reflect.TypeOf((*RemoteCallACK)(nil)).Elem(),
 		ID:    20476,
 	})
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*RemoteStreamFrame)(nil)).Elem(),
+		ID:    42920,
+	})
+	cellnet.RegisterMessageMeta(&cellnet.MessageMeta{
+		Codec: codec.MustGetCodec("protoplus"),
+		Type:  reflect.TypeOf((*RemoteStreamCtrl)(nil)).Elem(),
+		ID:    38258,
+	})
 }
@@ -0,0 +1,14 @@
+package rpc
+
+import (
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/proc"
+)
+
+// init 注册管线中的 rpc 钩子
+// 用法如 proc.BindProcessorHandler(peer, "tcp.ltv + rpc + msglog", callback)
+func init() {
+	proc.RegisterHooker("rpc", func(base string) cellnet.EventHooker {
+		return proc.NewResolverHooker("rpc", ResolveInboundEvent, ResolveOutboundEvent)
+	})
+}
@@ -0,0 +1,265 @@
+package rpc
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+// PickStrategy 客户端连接池选择 Peer 的策略
+type PickStrategy interface {
+	// Pick 从可用的 Peer 中选择一个
+	// candidates: 已就绪的 Peer 列表，不为空，顺序与加入连接池的顺序一致
+	// key: 请求的键，只有按键选择的策略使用，可以为空
+	// 返回选中的 Peer
+	Pick(candidates []cellnet.Peer, key string) cellnet.Peer
+}
+
+// RoundRobinStrategy 轮询选择
+type RoundRobinStrategy struct {
+	// next 下一次选择的序号
+	next uint64
+}
+
+// Pick 依次选择已就绪的 Peer（实现 PickStrategy 接口）
+func (self *RoundRobinStrategy) Pick(candidates []cellnet.Peer, key string) cellnet.Peer {
+	index := atomic.AddUint64(&self.next, 1) - 1
+
+	return candidates[index%uint64(len(candidates))]
+}
+
+// LeastPendingStrategy 选择待响应请求最少的 Peer
+// 待响应请求数量相同时，选择靠前的 Peer
+type LeastPendingStrategy struct {
+}
+
+// Pick 选择待响应请求最少的 Peer（实现 PickStrategy 接口）
+func (self LeastPendingStrategy) Pick(candidates []cellnet.Peer, key string) cellnet.Peer {
+	var (
+		ret   cellnet.Peer
+		least = -1
+	)
+
+	for _, p := range candidates {
+		count := pendingCount(peerRPCSession(p))
+
+		if least < 0 || count < least {
+			ret, least = p, count
+		}
+	}
+
+	return ret
+}
+
+// ConsistentHashStrategy 按请求的键选择 Peer
+// 使用最高随机权重（Rendezvous）哈希，以 Peer 的地址作为标识
+// 相同的键总是选中同一个 Peer；某个 Peer 不可用时，只有原本选中它的键会改为选择其他 Peer
+type ConsistentHashStrategy struct {
+}
+
+// Pick 按键的哈希选择 Peer（实现 PickStrategy 接口）
+func (self ConsistentHashStrategy) Pick(candidates []cellnet.Peer, key string) cellnet.Peer {
+	var (
+		ret cellnet.Peer
+		max uint64
+	)
+
+	for _, p := range candidates {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(peerAddress(p)))
+
+		if weight := h.Sum64(); ret == nil || weight > max {
+			ret, max = p, weight
+		}
+	}
+
+	return ret
+}
+
+// ClientPool RPC 客户端连接池
+// 持有多个连接到同一服务不同副本的连接器，每次请求按策略选择一个已就绪的连接器
+// 实现 RPCSessionGetter 接口，可以直接作为 Call、CallSync、CallContext 等函数的 sesOrPeer 参数
+// 没有已就绪的连接器时，请求返回 ErrEmptySession，异步请求在连接池的事件队列（见 Queue）中回调
+//
+// 使用示例:
+//
+//	pool := rpc.NewClientPool(&rpc.RoundRobinStrategy{}, connector1, connector2)
+//	ack, err := rpc.CallContext(ctx, pool, &LoginREQ{})
+//
+//	hashPool := rpc.NewClientPool(rpc.ConsistentHashStrategy{}, connector1, connector2)
+//	ack, err = rpc.CallContext(ctx, hashPool.WithKey(userID), &LoginREQ{})
+type ClientPool struct {
+	// guard 保护 peers
+	guard sync.RWMutex
+
+	// peers 连接池中的连接器
+	peers []cellnet.Peer
+
+	// strategy 选择策略
+	strategy PickStrategy
+}
+
+// Add 添加连接器
+// peers: 要添加的连接器，需要提供 Session() 方法，如 tcp.Connector
+// 已经在连接池中的连接器不会重复添加
+func (self *ClientPool) Add(peers ...cellnet.Peer) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	for _, p := range peers {
+		if self.indexOf(p) < 0 {
+			self.peers = append(self.peers, p)
+		}
+	}
+}
+
+// Remove 移除连接器
+// p: 要移除的连接器
+// 只从连接池中移除，不会停止连接器
+func (self *ClientPool) Remove(p cellnet.Peer) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if index := self.indexOf(p); index >= 0 {
+		self.peers = append(self.peers[:index:index], self.peers[index+1:]...)
+	}
+}
+
+// Peers 获取连接池中的所有连接器
+// 返回连接器列表的副本
+func (self *ClientPool) Peers() []cellnet.Peer {
+	self.guard.RLock()
+	defer self.guard.RUnlock()
+
+	return append([]cellnet.Peer(nil), self.peers...)
+}
+
+// Queue 获取连接池的事件队列
+// 返回第一个有事件队列的连接器的队列，没有时返回 nil
+// 没有已就绪的连接器时，异步请求在此队列中回调 ErrEmptySession
+func (self *ClientPool) Queue() cellnet.EventQueue {
+	self.guard.RLock()
+	defer self.guard.RUnlock()
+
+	for _, p := range self.peers {
+		if q := peerQueue(p); q != nil {
+			return q
+		}
+	}
+
+	return nil
+}
+
+// Pick 按策略选择一个已就绪的连接器，返回其 Session
+// key: 请求的键，用于 ConsistentHashStrategy 等按键选择的策略
+// 没有已就绪的连接器时返回 nil
+func (self *ClientPool) Pick(key string) cellnet.Session {
+	self.guard.RLock()
+
+	candidates := make([]cellnet.Peer, 0, len(self.peers))
+	for _, p := range self.peers {
+		if isPeerReady(p) {
+			candidates = append(candidates, p)
+		}
+	}
+
+	self.guard.RUnlock()
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return peerRPCSession(self.strategy.Pick(candidates, key))
+}
+
+// RPCSession 获取用于 RPC 的 Session（实现 RPCSessionGetter 接口）
+// 以空的键选择连接器
+func (self *ClientPool) RPCSession() cellnet.Session {
+	return self.Pick("")
+}
+
+// WithKey 返回按键选择连接器的 RPCSessionGetter
+// key: 请求的键，如用户 ID，用于 ConsistentHashStrategy 将同一个键的请求发往同一个副本
+func (self *ClientPool) WithKey(key string) RPCSessionGetter {
+	return keyedSessionGetter{pool: self, key: key}
+}
+
+// indexOf 查找连接器在连接池中的位置，不存在时返回 -1
+// 调用时需持有 guard
+func (self *ClientPool) indexOf(p cellnet.Peer) int {
+	for index, exist := range self.peers {
+		if exist == p {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// NewClientPool 创建 RPC 客户端连接池
+// strategy: 选择策略，为 nil 时使用 RoundRobinStrategy
+// peers: 初始的连接器
+func NewClientPool(strategy PickStrategy, peers ...cellnet.Peer) *ClientPool {
+	if strategy == nil {
+		strategy = &RoundRobinStrategy{}
+	}
+
+	self := &ClientPool{
+		strategy: strategy,
+	}
+
+	self.Add(peers...)
+
+	return self
+}
+
+// keyedSessionGetter 按键从连接池选择连接器
+type keyedSessionGetter struct {
+	pool *ClientPool
+	key  string
+}
+
+// RPCSession 获取用于 RPC 的 Session（实现 RPCSessionGetter 接口）
+func (self keyedSessionGetter) RPCSession() cellnet.Session {
+	return self.pool.Pick(self.key)
+}
+
+// Queue 获取连接池的事件队列
+func (self keyedSessionGetter) Queue() cellnet.EventQueue {
+	return self.pool.Queue()
+}
+
+// isPeerReady 检查连接器是否已就绪
+// 没有实现 PeerReadyChecker 的连接器视为已就绪
+func isPeerReady(p cellnet.Peer) bool {
+	if checker, ok := p.(cellnet.PeerReadyChecker); ok {
+		return checker.IsReady()
+	}
+
+	return true
+}
+
+// peerRPCSession 获取连接器的 Session
+// 连接器不提供 Session() 方法时返回 nil
+func peerRPCSession(p cellnet.Peer) cellnet.Session {
+	if getter, ok := p.(interface {
+		Session() cellnet.Session
+	}); ok {
+		return getter.Session()
+	}
+
+	return nil
+}
+
+// peerAddress 获取连接器的地址，用于一致性哈希
+func peerAddress(p cellnet.Peer) string {
+	if property, ok := p.(cellnet.PeerProperty); ok {
+		return property.Address()
+	}
+
+	return ""
+}
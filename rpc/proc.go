@@ -5,6 +5,8 @@ import (
 	"github.com/bobwong89757/cellnet/codec"
 	"github.com/bobwong89757/cellnet/log"
 	"github.com/bobwong89757/cellnet/msglog"
+	"io"
+	"time"
 )
 
 // RemoteCallMsg 远程调用消息接口
@@ -23,6 +25,9 @@ type RemoteCallMsg interface {
 // 如果是 RPC 消息，进行解码和处理：
 //   - RemoteCallREQ: 服务端收到客户端的请求，转换为 RecvMsgEvent
 //   - RemoteCallACK: 客户端收到服务器的回应，触发请求的回调
+//   - RemoteStreamFrame: 客户端收到流式请求的数据帧，交给对应的流
+//   - RemoteStreamCtrl: 服务器端收到流控制消息，补充接收窗口或取消流
+//   - SessionClosed: 以 ErrSessionClosed 结束该 Session 上所有待响应的请求，结束服务器端的流，取消服务器端请求的上下文
 // 返回处理后的输出事件、是否已处理、错误
 func ResolveInboundEvent(inputEvent cellnet.Event) (ouputEvent cellnet.Event, handled bool, err error) {
 
@@ -31,12 +36,61 @@ func ResolveInboundEvent(inputEvent cellnet.Event) (ouputEvent cellnet.Event, ha
 		return inputEvent, false, nil
 	}
 
+	// Session 关闭时，结束其上所有待响应的请求，并取消服务器端请求的上下文
+	if _, ok := inputEvent.Message().(*cellnet.SessionClosed); ok {
+		failSessionRequests(inputEvent.Session())
+		closeSessionStreams(inputEvent.Session())
+		cancelSessionContext(inputEvent.Session())
+		return inputEvent, false, nil
+	}
+
+	// 连接器重连成功时，重新创建服务器端请求的上下文
+	if _, ok := inputEvent.Message().(*cellnet.SessionConnected); ok {
+		resetSessionContext(inputEvent.Session())
+		return inputEvent, false, nil
+	}
+
+	// 客户端的流控制消息
+	if ctrl, ok := inputEvent.Message().(*RemoteStreamCtrl); ok {
+		if stream := getStream(inputEvent.Session(), ctrl.CallID); stream != nil {
+			stream.onCtrl(ctrl)
+		}
+
+		return inputEvent, true, nil
+	}
+
 	// 检查是否是 RPC 消息
 	rpcMsg, ok := inputEvent.Message().(RemoteCallMsg)
 	if !ok {
 		return inputEvent, false, nil
 	}
 
+	if ack, ok := rpcMsg.(*RemoteCallACK); ok {
+		// 服务器回应的错误，没有用户消息
+		if ack.ErrCode != 0 {
+			remoteErr := newRemoteError(ack)
+
+			writeErrorLog("#rpc.recv", inputEvent.Session(), remoteErr)
+
+			if request := getRequest(ack.CallID); request != nil {
+				request.RecvFeedback(remoteErr)
+			}
+
+			return inputEvent, true, nil
+		}
+
+		// 流式请求的结束标记，没有用户消息
+		if ack.MsgID == 0 {
+			writeStreamEndLog("#rpc.recv", inputEvent.Session(), ack.CallID)
+
+			if request := getRequest(ack.CallID); request != nil {
+				request.RecvFeedback(io.EOF)
+			}
+
+			return inputEvent, true, nil
+		}
+	}
+
 	// 解码用户消息
 	userMsg, _, err := codec.DecodeMessage(int(rpcMsg.GetMsgID()), rpcMsg.GetMsgData())
 
@@ -59,12 +113,27 @@ func ResolveInboundEvent(inputEvent cellnet.Event) (ouputEvent cellnet.Event, ha
 	// 根据消息类型处理
 	switch inputEvent.Message().(type) {
 	case *RemoteCallREQ: // 服务端收到客户端的请求
-		// 转换为 RecvMsgEvent，包含调用 ID
-		return &RecvMsgEvent{
-			inputEvent.Session(),
-			userMsg,
-			rpcMsg.GetCallID(),
-		}, true, nil
+		// 转换为 RecvMsgEvent，包含调用 ID 和调用方的截止时间
+		reqMsg := inputEvent.Message().(*RemoteCallREQ)
+
+		ev := &RecvMsgEvent{
+			ses:     inputEvent.Session(),
+			Msg:     userMsg,
+			callid:  rpcMsg.GetCallID(),
+			idemKey: reqMsg.IdemKey,
+		}
+
+		// 截止时间从收到请求的时间开始计算
+		if reqMsg.Timeout > 0 {
+			ev.deadline = time.Now().Add(time.Duration(reqMsg.Timeout) * time.Millisecond)
+		}
+
+		// 流式请求，按客户端的接收窗口创建服务器端的流
+		if reqMsg.Window != 0 {
+			ev.stream = newServerStream(ev, reqMsg.Window)
+		}
+
+		return ev, true, nil
 
 	case *RemoteCallACK: // 客户端收到服务器的回应
 		// 查找对应的请求并触发回调
@@ -73,6 +142,15 @@ func ResolveInboundEvent(inputEvent cellnet.Event) (ouputEvent cellnet.Event, ha
 			request.RecvFeedback(userMsg)
 		}
 
+		return inputEvent, true, nil
+
+	case *RemoteStreamFrame: // 客户端收到流式请求的数据帧
+		// 查找对应的请求，数据帧不结束请求
+		request := peekRequest(rpcMsg.GetCallID())
+		if request != nil && request.onFrame != nil {
+			request.onFrame(userMsg)
+		}
+
 		return inputEvent, true, nil
 	}
 
@@ -90,6 +168,20 @@ func ResolveOutboundEvent(inputEvent cellnet.Event) (handled bool, err error) {
 		return false, nil
 	}
 
+	if ack, ok := rpcMsg.(*RemoteCallACK); ok {
+		// 错误回应，没有用户消息
+		if ack.ErrCode != 0 {
+			writeErrorLog("#rpc.send", inputEvent.Session(), newRemoteError(ack))
+			return true, nil
+		}
+
+		// 流式请求的结束标记，没有用户消息
+		if ack.MsgID == 0 {
+			writeStreamEndLog("#rpc.send", inputEvent.Session(), ack.CallID)
+			return true, nil
+		}
+	}
+
 	// 解码用户消息（用于日志）
 	userMsg, _, err := codec.DecodeMessage(int(rpcMsg.GetMsgID()), rpcMsg.GetMsgData())
 
@@ -113,3 +205,32 @@ func ResolveOutboundEvent(inputEvent cellnet.Event) (handled bool, err error) {
 
 	return true, nil
 }
+
+// writeErrorLog 记录 RPC 错误回应的日志
+// tag: 日志标签，#rpc.recv 或 #rpc.send
+// ses: 会话对象
+// remoteErr: 错误回应
+func writeErrorLog(tag string, ses cellnet.Session, remoteErr *RemoteError) {
+	peerInfo := ses.Peer().(cellnet.PeerProperty)
+
+	log.GetLog().Debugf("%s(%s)@%d %s | %s",
+		tag,
+		peerInfo.Name(),
+		ses.ID(),
+		remoteErr.Error(),
+		cellnet.MessageToString(remoteErr.Detail))
+}
+
+// writeStreamEndLog 记录流式请求结束标记的日志
+// tag: 日志标签，#rpc.recv 或 #rpc.send
+// ses: 会话对象
+// callid: 流式请求的调用 ID
+func writeStreamEndLog(tag string, ses cellnet.Session, callid int64) {
+	peerInfo := ses.Peer().(cellnet.PeerProperty)
+
+	log.GetLog().Debugf("%s(%s)@%d stream end callid: %d",
+		tag,
+		peerInfo.Name(),
+		ses.ID(),
+		callid)
+}
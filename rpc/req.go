@@ -5,8 +5,10 @@ import (
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/codec"
 	"github.com/bobwong89757/cellnet/log"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var (
@@ -14,10 +16,16 @@ var (
 	// 使用原子操作保证并发安全，每次创建请求时递增
 	rpcIDSeq int64
 
+	// requestGuard 保护 requestByCallID 和 requestBySession
+	requestGuard sync.Mutex
+
 	// requestByCallID 存储所有待响应的 RPC 请求
 	// 键为请求 ID（int64），值为 request 实例
-	// 使用 sync.Map 保证并发安全
-	requestByCallID sync.Map
+	requestByCallID = map[int64]*request{}
+
+	// requestBySession 按 Session 存储待响应的 RPC 请求
+	// Session 关闭时，用于找到并结束该 Session 上所有待响应的请求
+	requestBySession = map[cellnet.Session]map[int64]*request{}
 )
 
 // request 表示一个 RPC 请求
@@ -26,13 +34,40 @@ type request struct {
 	// id 请求的唯一标识符
 	id int64
 
+	// ses 发送请求的 Session
+	ses cellnet.Session
+
+	// msgName 请求消息的名称，用于调试
+	msgName string
+
+	// createTime 请求的创建时间
+	createTime time.Time
+
+	// deadline 请求的截止时间，剩余的超时时间随请求发送给服务器，零值表示没有截止时间
+	deadline time.Time
+
+	// window 流式请求的初始接收窗口，0 表示普通请求
+	window uint32
+
+	// idemKey 幂等键，重试的请求使用相同的键，服务器据此去重
+	idemKey string
+
 	// onRecv 接收到响应时的回调函数
-	// 参数为响应消息
+	// 参数为响应消息，或 ErrTimeout、ErrSessionClosed 等错误
+	// 流式请求结束时参数为 io.EOF
 	onRecv func(interface{})
+
+	// onFrame 流式请求接收到数据帧时的回调函数
+	onFrame func(interface{})
 }
 
-// ErrTimeout 表示 RPC 请求超时的错误
-var ErrTimeout = errors.New("RPC time out")
+var (
+	// ErrTimeout 表示 RPC 请求超时的错误
+	ErrTimeout = errors.New("RPC time out")
+
+	// ErrSessionClosed 表示 RPC 请求未收到响应前 Session 已关闭的错误
+	ErrSessionClosed = errors.New("RPC session closed")
+)
 
 // RecvFeedback 接收 RPC 响应反馈
 // msg: 响应消息
@@ -56,31 +91,69 @@ func (self *request) Send(ses cellnet.Session, msg interface{}) {
 		return
 	}
 
+	reqMsg := &RemoteCallREQ{
+		MsgID:   uint32(meta.ID),
+		Data:    data,
+		CallID:  self.id,
+		Window:  self.window,
+		IdemKey: self.idemKey,
+	}
+
+	// 发送剩余的超时时间（毫秒），服务器以收到请求的时间计算截止时间，不受双方时钟偏差的影响
+	if !self.deadline.IsZero() {
+		reqMsg.Timeout = remainingMilli(self.deadline)
+	}
+
 	// 发送 RPC 请求消息
-	ses.Send(&RemoteCallREQ{
-		MsgID:  uint32(meta.ID),
-		Data:   data,
-		CallID: self.id,
-	})
+	ses.Send(reqMsg)
 
 	// 注意：如果需要释放 Codec 资源，可以在这里调用
 	// codec.FreeCodecResource(meta.Codec, data, ctx)
 }
 
+// remainingMilli 获取距离截止时间的剩余毫秒数，向上取整
+// 已经超过截止时间时返回 1，0 表示没有截止时间
+func remainingMilli(deadline time.Time) int64 {
+	remaining := (time.Until(deadline) + time.Millisecond - 1) / time.Millisecond
+	if remaining < 1 {
+		return 1
+	}
+
+	return int64(remaining)
+}
+
 // createRequest 创建一个新的 RPC 请求
+// ses: 发送请求的 Session
+// reqMsg: 请求消息对象
 // onRecv: 接收到响应时的回调函数
 // 返回创建的 request 实例
-// 请求会被分配一个唯一的 ID 并存储到全局映射表中
-func createRequest(onRecv func(interface{})) *request {
+// 请求会被分配一个唯一的 ID 并存储到全局映射表和 Session 的请求表中
+func createRequest(ses cellnet.Session, reqMsg interface{}, onRecv func(interface{})) *request {
 	self := &request{
-		onRecv: onRecv,
+		ses:        ses,
+		msgName:    cellnet.MessageToName(reqMsg),
+		createTime: time.Now(),
+		onRecv:     onRecv,
 	}
 
 	// 生成唯一的请求 ID
 	self.id = atomic.AddInt64(&rpcIDSeq, 1)
 
+	requestGuard.Lock()
+
 	// 存储到全局映射表
-	requestByCallID.Store(self.id, self)
+	requestByCallID[self.id] = self
+
+	// 存储到 Session 的请求表
+	reqByID := requestBySession[ses]
+	if reqByID == nil {
+		reqByID = map[int64]*request{}
+		requestBySession[ses] = reqByID
+	}
+
+	reqByID[self.id] = self
+
+	requestGuard.Unlock()
 
 	return self
 }
@@ -90,11 +163,114 @@ func createRequest(onRecv func(interface{})) *request {
 // 返回对应的 request 实例，如果不存在返回 nil
 // 获取后会将请求从映射表中删除
 func getRequest(callid int64) *request {
-	if v, ok := requestByCallID.Load(callid); ok {
-		// 从映射表中删除
-		requestByCallID.Delete(callid)
-		return v.(*request)
+	requestGuard.Lock()
+	defer requestGuard.Unlock()
+
+	self, ok := requestByCallID[callid]
+	if !ok {
+		return nil
+	}
+
+	// 从映射表中删除
+	delete(requestByCallID, callid)
+
+	if reqByID := requestBySession[self.ses]; reqByID != nil {
+		delete(reqByID, callid)
+
+		if len(reqByID) == 0 {
+			delete(requestBySession, self.ses)
+		}
 	}
 
-	return nil
+	return self
+}
+
+// peekRequest 根据请求 ID 获取 RPC 请求，不移除
+// callid: 请求的唯一标识符
+// 用于流式请求接收数据帧
+func peekRequest(callid int64) *request {
+	requestGuard.Lock()
+	defer requestGuard.Unlock()
+
+	return requestByCallID[callid]
+}
+
+// failSessionRequests 结束 Session 上所有待响应的 RPC 请求
+// ses: 已关闭的 Session
+// 每个请求的回调会收到 ErrSessionClosed
+func failSessionRequests(ses cellnet.Session) {
+	requestGuard.Lock()
+
+	reqByID := requestBySession[ses]
+	delete(requestBySession, ses)
+
+	for callid := range reqByID {
+		delete(requestByCallID, callid)
+	}
+
+	requestGuard.Unlock()
+
+	for _, req := range reqByID {
+		req.RecvFeedback(ErrSessionClosed)
+	}
+}
+
+// pendingCount 获取 Session 上待响应的 RPC 请求数量
+// ses: 发送请求的 Session
+func pendingCount(ses cellnet.Session) int {
+	requestGuard.Lock()
+	defer requestGuard.Unlock()
+
+	return len(requestBySession[ses])
+}
+
+// PendingCall 待响应的 RPC 请求信息
+// 用于调试和监控
+type PendingCall struct {
+	// CallID 请求的调用 ID
+	CallID int64
+
+	// Session 发送请求的 Session
+	Session cellnet.Session
+
+	// MsgName 请求消息的名称
+	MsgName string
+
+	// CreateTime 请求的创建时间
+	CreateTime time.Time
+}
+
+// PendingCalls 列出待响应的 RPC 请求
+// ses: 只列出此 Session 上的请求，为 nil 时列出所有请求
+// 返回按调用 ID 递增排列的请求信息
+func PendingCalls(ses cellnet.Session) (ret []PendingCall) {
+	requestGuard.Lock()
+
+	if ses != nil {
+		for _, req := range requestBySession[ses] {
+			ret = append(ret, req.pendingCall())
+		}
+	} else {
+		for _, req := range requestByCallID {
+			ret = append(ret, req.pendingCall())
+		}
+	}
+
+	requestGuard.Unlock()
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].CallID < ret[j].CallID
+	})
+
+	return
+}
+
+// pendingCall 返回请求的调试信息
+func (self *request) pendingCall() PendingCall {
+	return PendingCall{
+		CallID:     self.id,
+		Session:    self.ses,
+		MsgName:    self.msgName,
+		CreateTime: self.createTime,
+	}
 }
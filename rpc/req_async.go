@@ -9,28 +9,75 @@ import (
 // sesOrPeer: Session 或 Peer，用于发送请求
 // reqMsg: 请求消息对象
 // timeout: 超时时间，如果在此时间内未收到响应，会调用回调并传入超时错误
-// userCallback: 响应回调函数，参数为响应消息或错误
+// userCallback: 响应回调函数，参数为响应消息或错误（ErrTimeout、ErrSessionClosed 等）
 //   回调函数会在 Session 对应 Peer 的事件队列中执行，保证线程安全
+//   没有可用的 Session 时以 ErrEmptySession 等错误回调，在 sesOrPeer 的事件队列中执行，没有队列时直接执行
+//   请求类型设置了重试策略时，可重试的错误会自动重试，每次尝试的超时时间为 timeout
 // 此方法不会阻塞，立即返回
 func Call(sesOrPeer interface{}, reqMsg interface{}, timeout time.Duration, userCallback func(raw interface{})) {
+	policy := retryPolicyOf(reqMsg)
+
+	var (
+		key     string
+		attempt int
+		try     func()
+	)
+
+	if policy != nil {
+		key = newIdempotencyKey()
+	}
+
+	try = func() {
+		attempt++
+
+		ses, err := callAsync(sesOrPeer, reqMsg, timeout, key, func(raw interface{}) {
+			if err, ok := raw.(error); ok && policy != nil && policy.shouldRetry(attempt, err) {
+				time.AfterFunc(policy.delay(attempt), try)
+				return
+			}
+
+			userCallback(raw)
+		})
+
+		if err != nil {
+			if policy != nil && policy.shouldRetry(attempt, err) {
+				time.AfterFunc(policy.delay(attempt), try)
+				return
+			}
+
+			// 获取 Session 失败，在队列中调用回调并传入错误
+			queuedCall(sesOrPeer, ses, func() {
+				userCallback(err)
+			})
+		}
+	}
+
+	try()
+}
+
+// callAsync 发送一次异步 RPC 请求
+// key: 幂等键，没有重试策略时为空
+// callback: 响应回调函数，在 Session 对应 Peer 的事件队列中执行
+// 获取 Session 失败时不调用回调，返回错误
+func callAsync(sesOrPeer interface{}, reqMsg interface{}, timeout time.Duration, key string, callback func(raw interface{})) (cellnet.Session, error) {
 	// 获取 Session
 	ses, err := getPeerSession(sesOrPeer)
 
 	if err != nil {
-		// 获取 Session 失败，在队列中调用回调并传入错误
-		cellnet.SessionQueuedCall(ses, func() {
-			userCallback(err)
-		})
-		return
+		return ses, err
 	}
 
 	// 创建 RPC 请求，响应时在队列中调用回调
-	req := createRequest(func(raw interface{}) {
+	req := createRequest(ses, reqMsg, func(raw interface{}) {
 		cellnet.SessionQueuedCall(ses, func() {
-			userCallback(raw)
+			callback(raw)
 		})
 	})
 
+	// 超时时间作为截止时间发送给服务器
+	req.deadline = time.Now().Add(timeout)
+	req.idemKey = key
+
 	// 发送 RPC 请求
 	req.Send(ses, reqMsg)
 
@@ -39,8 +86,10 @@ func Call(sesOrPeer interface{}, reqMsg interface{}, timeout time.Duration, user
 		// 取出请求，如果存在，说明请求还未收到响应，调用超时回调
 		if getRequest(req.id) != nil {
 			cellnet.SessionQueuedCall(ses, func() {
-				userCallback(ErrTimeout)
+				callback(ErrTimeout)
 			})
 		}
 	})
+
+	return ses, nil
 }
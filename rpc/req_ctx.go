@@ -0,0 +1,191 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+// CallContext 执行同步 RPC 请求，由 context 控制取消和超时
+// ctx: 请求的上下文，取消或超过截止时间时请求结束
+// sesOrPeer: Session 或 Peer，用于发送请求
+// reqMsg: 请求消息对象
+// 返回响应消息和错误信息，ctx 结束时返回 ctx.Err()
+// ctx 的截止时间会随请求发送给服务器，服务器可以通过 RecvMsgEvent.Context 获得
+// 请求类型设置了重试策略时，可重试的错误会在 ctx 结束前自动重试
+// 此方法会阻塞当前 goroutine，直到收到响应、ctx 结束或 Session 关闭
+func CallContext(ctx context.Context, sesOrPeer interface{}, reqMsg interface{}) (interface{}, error) {
+	policy := retryPolicyOf(reqMsg)
+	if policy == nil {
+		return callContext(ctx, sesOrPeer, reqMsg, "")
+	}
+
+	// 所有尝试使用相同的幂等键
+	key := newIdempotencyKey()
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.AttemptTimeout)
+		}
+
+		ack, err := callContext(attemptCtx, sesOrPeer, reqMsg, key)
+		cancel()
+
+		// ctx 本身结束时不再重试
+		if ctx.Err() != nil || !policy.shouldRetry(attempt, err) {
+			return ack, err
+		}
+
+		if !sleepContext(ctx, policy.delay(attempt)) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// callContext 发送一次由 context 控制的同步 RPC 请求
+// key: 幂等键，没有重试策略时为空
+func callContext(ctx context.Context, sesOrPeer interface{}, reqMsg interface{}, key string) (interface{}, error) {
+	// 获取 Session
+	ses, err := getPeerSession(sesOrPeer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 已经取消的请求不再发送
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// 创建响应通道，带缓冲以免取消后响应方阻塞
+	ret := make(chan interface{}, 1)
+	// 创建 RPC 请求，响应时通过通道返回
+	req := createRequest(ses, reqMsg, func(feedbackMsg interface{}) {
+		ret <- feedbackMsg
+	})
+
+	req.deadline, _ = ctx.Deadline()
+	req.idemKey = key
+
+	// 发送 RPC 请求
+	req.Send(ses, reqMsg)
+
+	var v interface{}
+
+	// 等待 RPC 回复或 ctx 结束
+	select {
+	case v = <-ret:
+	case <-ctx.Done():
+		// 请求已被取出时，响应或错误正在送达，以其为准
+		if getRequest(req.id) != nil {
+			return nil, ctx.Err()
+		}
+
+		v = <-ret
+	}
+
+	// Session 关闭等错误
+	if err, ok := v.(error); ok {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// CallContextAsync 执行异步 RPC 请求，由 context 控制取消和超时
+// ctx: 请求的上下文，取消或超过截止时间时，回调收到 ctx.Err()
+// sesOrPeer: Session 或 Peer，用于发送请求
+// reqMsg: 请求消息对象
+// userCallback: 响应回调函数，参数为响应消息或错误，在 Session 对应 Peer 的事件队列中执行
+// 请求类型设置了重试策略时，可重试的错误会在 ctx 结束前自动重试，所有尝试使用相同的幂等键
+// 此方法不会阻塞，立即返回
+func CallContextAsync(ctx context.Context, sesOrPeer interface{}, reqMsg interface{}, userCallback func(raw interface{})) {
+	policy := retryPolicyOf(reqMsg)
+	if policy == nil {
+		callContextAsync(ctx, sesOrPeer, reqMsg, "", userCallback)
+		return
+	}
+
+	var (
+		// 所有尝试使用相同的幂等键
+		key     = newIdempotencyKey()
+		attempt int
+		try     func()
+	)
+
+	try = func() {
+		attempt++
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.AttemptTimeout)
+		}
+
+		callContextAsync(attemptCtx, sesOrPeer, reqMsg, key, func(raw interface{}) {
+			cancel()
+
+			// ctx 本身结束时不再重试
+			if err, ok := raw.(error); ok && ctx.Err() == nil && policy.shouldRetry(attempt, err) {
+				time.AfterFunc(policy.delay(attempt), try)
+				return
+			}
+
+			userCallback(raw)
+		})
+	}
+
+	try()
+}
+
+// callContextAsync 发送一次由 context 控制的异步 RPC 请求
+// key: 幂等键，没有重试策略时为空
+// callback: 响应回调函数，获取 Session 失败、ctx 结束或收到响应时在队列中调用
+func callContextAsync(ctx context.Context, sesOrPeer interface{}, reqMsg interface{}, key string, userCallback func(raw interface{})) {
+	// 获取 Session
+	ses, err := getPeerSession(sesOrPeer)
+
+	if err != nil {
+		// 获取 Session 失败，在队列中调用回调并传入错误
+		queuedCall(sesOrPeer, ses, func() {
+			userCallback(err)
+		})
+		return
+	}
+
+	// 已经取消的请求不再发送
+	if err := ctx.Err(); err != nil {
+		cellnet.SessionQueuedCall(ses, func() {
+			userCallback(err)
+		})
+		return
+	}
+
+	var stop func() bool
+
+	// 创建 RPC 请求，响应时在队列中调用回调
+	req := createRequest(ses, reqMsg, func(raw interface{}) {
+		// 已经收到响应，不再监听 ctx
+		stop()
+
+		cellnet.SessionQueuedCall(ses, func() {
+			userCallback(raw)
+		})
+	})
+
+	req.deadline, _ = ctx.Deadline()
+	req.idemKey = key
+
+	// ctx 结束时，如果请求还未收到响应，调用回调并传入 ctx 的错误
+	stop = context.AfterFunc(ctx, func() {
+		if getRequest(req.id) != nil {
+			cellnet.SessionQueuedCall(ses, func() {
+				userCallback(ctx.Err())
+			})
+		}
+	})
+
+	// 发送 RPC 请求
+	req.Send(ses, reqMsg)
+}
@@ -9,8 +9,30 @@ import (
 // reqMsg: 请求消息对象
 // timeout: 超时时间，如果在此时间内未收到响应，返回超时错误
 // 返回响应消息和错误信息
-// 此方法会阻塞当前 goroutine，直到收到响应或超时
+// 请求类型设置了重试策略时，可重试的错误会自动重试，每次尝试的超时时间为 timeout
+// 此方法会阻塞当前 goroutine，直到收到响应、超时或 Session 关闭
 func CallSync(ud interface{}, reqMsg interface{}, timeout time.Duration) (interface{}, error) {
+	policy := retryPolicyOf(reqMsg)
+	if policy == nil {
+		return callSync(ud, reqMsg, timeout, "")
+	}
+
+	// 所有尝试使用相同的幂等键
+	key := newIdempotencyKey()
+
+	for attempt := 1; ; attempt++ {
+		ack, err := callSync(ud, reqMsg, timeout, key)
+		if !policy.shouldRetry(attempt, err) {
+			return ack, err
+		}
+
+		time.Sleep(policy.delay(attempt))
+	}
+}
+
+// callSync 发送一次同步 RPC 请求
+// key: 幂等键，没有重试策略时为空
+func callSync(ud interface{}, reqMsg interface{}, timeout time.Duration, key string) (interface{}, error) {
 	// 获取 Session
 	ses, err := getPeerSession(ud)
 
@@ -18,19 +40,28 @@ func CallSync(ud interface{}, reqMsg interface{}, timeout time.Duration) (interf
 		return nil, err
 	}
 
-	// 创建响应通道
-	ret := make(chan interface{})
+	// 创建响应通道，带缓冲以免超时后响应方阻塞
+	ret := make(chan interface{}, 1)
 	// 创建 RPC 请求，响应时通过通道返回
-	req := createRequest(func(feedbackMsg interface{}) {
+	req := createRequest(ses, reqMsg, func(feedbackMsg interface{}) {
 		ret <- feedbackMsg
 	})
 
+	// 超时时间作为截止时间发送给服务器
+	req.deadline = time.Now().Add(timeout)
+	req.idemKey = key
+
 	// 发送 RPC 请求
 	req.Send(ses, reqMsg)
 
 	// 等待 RPC 回复或超时
 	select {
 	case v := <-ret:
+		// Session 关闭等错误
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+
 		// 收到响应，返回响应消息
 		return v, nil
 	case <-time.After(timeout):
@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+// RetryPolicy RPC 请求的重试策略
+// 通过 SetRetryPolicy 为请求类型设置后，Call、CallSync、CallContext、CallContextAsync、Invoke、InvokeAsync 和 CallType 等请求在可重试的错误时自动重试
+// 流式请求的数据帧无法重放，CallStream 不支持重试，请求类型设置了重试策略时返回 ErrRetryNotSupported
+// 同一次调用的所有尝试携带相同的幂等键，服务器端使用 DedupInterceptor 保证请求只执行一次
+type RetryPolicy struct {
+	// MaxAttempts 最多尝试次数，包含第一次请求，小于等于 1 时不重试
+	MaxAttempts int
+
+	// MinDelay 第一次重试前的等待时间
+	MinDelay time.Duration
+
+	// MaxDelay 重试等待时间的上限，0 表示没有上限
+	MaxDelay time.Duration
+
+	// Factor 每次重试后等待时间的增长倍数，小于 1 时使用 2
+	Factor float64
+
+	// AttemptTimeout CallContext、CallContextAsync、Invoke 和 InvokeAsync 每次尝试的超时时间，0 表示只受 ctx 控制
+	// Call 和 CallSync 使用参数中的超时时间作为每次尝试的超时时间
+	AttemptTimeout time.Duration
+
+	// Retryable 判断错误是否可以重试，为 nil 时使用 IsRetryable
+	Retryable func(err error) bool
+}
+
+// IsRetryable 默认的可重试错误判断
+// 超时、Session 关闭和没有可用的 Session 可以重试，服务器回应的错误不重试
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrTimeout) ||
+		errors.Is(err, ErrSessionClosed) ||
+		errors.Is(err, ErrEmptySession) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// delay 获取第 attempt 次尝试失败后的等待时间
+func (self *RetryPolicy) delay(attempt int) time.Duration {
+	factor := self.Factor
+	if factor < 1 {
+		factor = 2
+	}
+
+	delay := float64(self.MinDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= factor
+
+		if self.MaxDelay > 0 && delay >= float64(self.MaxDelay) {
+			return self.MaxDelay
+		}
+	}
+
+	return time.Duration(delay)
+}
+
+// shouldRetry 判断第 attempt 次尝试的错误是否需要重试
+func (self *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if err == nil || attempt >= self.MaxAttempts {
+		return false
+	}
+
+	if self.Retryable != nil {
+		return self.Retryable(err)
+	}
+
+	return IsRetryable(err)
+}
+
+var (
+	// retryPolicyGuard 保护 retryPolicyByMeta
+	retryPolicyGuard sync.RWMutex
+
+	// retryPolicyByMeta 按请求消息类型存储重试策略
+	retryPolicyByMeta = map[*cellnet.MessageMeta]*RetryPolicy{}
+)
+
+// SetRetryPolicy 为请求消息类型设置重试策略
+// msgName: 请求消息的完整名称，格式为 "包名.类型名"
+// policy: 重试策略，为 nil 时取消重试
+// 如果消息未注册到消息元信息表，会触发 panic
+func SetRetryPolicy(msgName string, policy *RetryPolicy) {
+	meta := cellnet.MessageMetaByFullName(msgName)
+	if meta == nil {
+		panic("message not found:" + msgName)
+	}
+
+	retryPolicyGuard.Lock()
+	defer retryPolicyGuard.Unlock()
+
+	if policy == nil {
+		delete(retryPolicyByMeta, meta)
+	} else {
+		retryPolicyByMeta[meta] = policy
+	}
+}
+
+// retryPolicyOf 获取请求消息的重试策略，没有设置时返回 nil
+func retryPolicyOf(reqMsg interface{}) *RetryPolicy {
+	meta := cellnet.MessageMetaByMsg(reqMsg)
+	if meta == nil {
+		return nil
+	}
+
+	retryPolicyGuard.RLock()
+	defer retryPolicyGuard.RUnlock()
+
+	return retryPolicyByMeta[meta]
+}
+
+// newIdempotencyKey 创建随机的幂等键
+func newIdempotencyKey() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+
+	return hex.EncodeToString(buf[:])
+}
+
+// sleepContext 等待一段时间，ctx 结束时提前返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
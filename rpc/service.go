@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/log"
+	"github.com/bobwong89757/cellnet/proc"
+)
+
+const (
+	// ErrCodeInternal 服务器内部错误，处理函数返回普通错误或发生 panic 时使用
+	ErrCodeInternal int32 = -2
+
+	// ErrCodeUnauthenticated 请求未通过认证
+	ErrCodeUnauthenticated int32 = -3
+)
+
+// HandlerFunc RPC 服务处理函数
+// ev: 收到的 RPC 请求事件
+// 返回响应消息和错误
+// 返回错误时自动回应错误，*RemoteError 保留错误码和详细信息，其他错误使用 ErrCodeInternal
+// 返回响应消息时自动回应，都为 nil 时表示处理函数自行回应（例如异步处理）
+type HandlerFunc func(ev *RecvMsgEvent) (ack interface{}, err error)
+
+// Interceptor RPC 服务拦截器
+// ev: 收到的 RPC 请求事件
+// next: 后续的拦截器和处理函数
+// 拦截器可以在调用 next 前后执行逻辑，也可以不调用 next 直接返回
+// 用于认证、日志、统计、panic 恢复等
+type Interceptor func(ev *RecvMsgEvent, next HandlerFunc) (ack interface{}, err error)
+
+// Service RPC 服务
+// 按请求消息类型注册处理函数，处理函数的返回值自动作为响应或错误回应
+// 基于 proc.MessageDispatcher 派发，请求需经过 ResolveInboundEvent 转换为 RecvMsgEvent
+type Service struct {
+	// dispatcher 消息派发器
+	dispatcher *proc.MessageDispatcher
+
+	// interceptors 拦截器列表，先添加的在外层
+	interceptors []Interceptor
+}
+
+// Use 添加拦截器
+// interceptors: 拦截器列表
+// 先添加的拦截器在外层，先于后添加的拦截器执行
+// 对所有处理函数生效，应在 Peer 开始收到请求前调用
+func (self *Service) Use(interceptors ...Interceptor) {
+	self.interceptors = append(self.interceptors, interceptors...)
+}
+
+// Dispatcher 返回服务使用的消息派发器
+// 可以用于注册非 RPC 消息的处理函数
+func (self *Service) Dispatcher() *proc.MessageDispatcher {
+	return self.dispatcher
+}
+
+// OnEvent 处理事件
+// ev: 要处理的事件
+// 可以作为处理器的用户回调，或在其他回调中转发事件
+func (self *Service) OnEvent(ev cellnet.Event) {
+	self.dispatcher.OnEvent(ev)
+}
+
+// Handle 注册请求消息的处理函数
+// msgName: 请求消息的完整名称，格式为 "包名.类型名"
+// handler: 处理函数
+// 如果消息未注册到消息元信息表，会触发 panic
+func (self *Service) Handle(msgName string, handler HandlerFunc) {
+	meta := cellnet.MessageMetaByFullName(msgName)
+	if meta == nil {
+		panic("message not found:" + msgName)
+	}
+
+	self.handle(meta, handler)
+}
+
+// handle 为请求消息注册处理函数
+// 处理函数的返回值自动作为响应或错误回应
+func (self *Service) handle(meta *cellnet.MessageMeta, handler HandlerFunc) {
+	self.dispatcher.RegisterMessage(meta.FullName(), func(ev cellnet.Event) {
+		rpcEv, ok := ev.(*RecvMsgEvent)
+		if !ok {
+			log.GetLog().Warnf("rpc service ignore non-rpc message: %s", meta.TypeName())
+			return
+		}
+
+		ack, err := self.invoke(rpcEv, handler, 0)
+
+		switch {
+		case err != nil:
+			var remoteErr *RemoteError
+			if errors.As(err, &remoteErr) {
+				rpcEv.ReplyError(remoteErr.Code, remoteErr.Message, remoteErr.Detail)
+			} else {
+				rpcEv.ReplyError(ErrCodeInternal, err.Error(), nil)
+			}
+		case ack != nil:
+			rpcEv.Reply(ack)
+		}
+	})
+}
+
+// invoke 依次经过拦截器后调用处理函数
+// index: 下一个执行的拦截器索引
+func (self *Service) invoke(ev *RecvMsgEvent, handler HandlerFunc, index int) (interface{}, error) {
+	if index >= len(self.interceptors) {
+		return handler(ev)
+	}
+
+	return self.interceptors[index](ev, func(ev *RecvMsgEvent) (interface{}, error) {
+		return self.invoke(ev, handler, index+1)
+	})
+}
+
+// Register 注册类型安全的请求处理函数
+// Req: 请求消息类型
+// svc: RPC 服务
+// handler: 处理函数，req 为已转换类型的请求消息
+// 如果请求消息类型未注册到消息元信息表，会触发 panic
+//
+// 使用示例:
+//
+//	rpc.Register(svc, func(ev *rpc.RecvMsgEvent, req *LoginREQ) (interface{}, error) {
+//		return &LoginACK{}, nil
+//	})
+func Register[Req any](svc *Service, handler func(ev *RecvMsgEvent, req *Req) (interface{}, error)) {
+	meta := cellnet.MessageMetaByType(reflect.TypeOf((*Req)(nil)).Elem())
+	if meta == nil {
+		panic("message not found:" + reflect.TypeOf((*Req)(nil)).Elem().String())
+	}
+
+	svc.handle(meta, func(ev *RecvMsgEvent) (interface{}, error) {
+		return handler(ev, ev.Msg.(*Req))
+	})
+}
+
+// NewService 创建 RPC 服务
+func NewService() *Service {
+	return &Service{
+		dispatcher: proc.NewMessageDispatcher(),
+	}
+}
+
+// NewServiceBindPeer 创建 RPC 服务并绑定到 Peer
+// peer: 要绑定的 Peer
+// processorName: 处理器的名称，需要带有 RPC 功能，如 "tcp.ltv"
+// 返回创建并绑定好的 Service
+func NewServiceBindPeer(peer cellnet.Peer, processorName string) *Service {
+	return &Service{
+		dispatcher: proc.NewMessageDispatcherBindPeer(peer, processorName),
+	}
+}
@@ -0,0 +1,229 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+
+	"github.com/bobwong89757/cellnet"
+)
+
+var (
+	// ErrStreamClosed 表示流已经关闭
+	// 客户端调用 Close 后继续接收，或服务器端结束、被取消后继续发送时返回
+	ErrStreamClosed = errors.New("rpc: Stream closed")
+
+	// ErrStreamOverflow 表示对方发送的数据帧超过了接收窗口
+	ErrStreamOverflow = errors.New("rpc: Stream window overflow")
+
+	// ErrRetryNotSupported 表示流式请求的请求类型设置了重试策略
+	// 流式请求的数据帧无法重放，不支持重试
+	ErrRetryNotSupported = errors.New("rpc: Retry policy not supported by stream request")
+)
+
+// DefaultStreamWindow 流式请求默认的接收窗口大小
+const DefaultStreamWindow = 64
+
+// streamItem 客户端流接收到的数据帧或结束错误
+type streamItem struct {
+	msg interface{}
+	err error
+}
+
+// ClientStream 客户端的流式请求
+// 服务器对一个请求回应多个数据帧，最后以结束标记或错误结束
+// 使用接收窗口做流量控制：服务器最多发送窗口大小个未被接收的数据帧，客户端每接收一部分数据帧后补充窗口
+// Recv 和 All 只能在一个 goroutine 中使用
+type ClientStream struct {
+	// ses 发送请求的 Session
+	ses cellnet.Session
+
+	// req 对应的 RPC 请求
+	req *request
+
+	// ctx 请求的上下文
+	ctx context.Context
+
+	// stopCtx 停止监听 ctx
+	stopCtx func() bool
+
+	// items 已接收但未读取的数据帧，最后一项为结束错误
+	items chan streamItem
+
+	// window 接收窗口大小
+	window int
+
+	// consumed 上次补充窗口后读取的数据帧数量
+	consumed int
+
+	// err 已读取到的结束错误，结束后 Recv 总是返回此错误
+	err error
+}
+
+// CallStream 发起流式 RPC 请求
+// ctx: 请求的上下文，取消时结束接收并通知服务器取消
+// sesOrPeer: Session 或 Peer，用于发送请求
+// reqMsg: 请求消息对象
+// window: 接收窗口大小，小于等于 0 时使用 DefaultStreamWindow
+// 返回客户端流，通过 Recv 或 All 读取数据帧
+// 请求类型设置了重试策略时返回 ErrRetryNotSupported
+func CallStream(ctx context.Context, sesOrPeer interface{}, reqMsg interface{}, window int) (*ClientStream, error) {
+	if retryPolicyOf(reqMsg) != nil {
+		return nil, ErrRetryNotSupported
+	}
+
+	// 获取 Session
+	ses, err := getPeerSession(sesOrPeer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 已经取消的请求不再发送
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if window <= 0 {
+		window = DefaultStreamWindow
+	}
+
+	self := &ClientStream{
+		ses:    ses,
+		ctx:    ctx,
+		window: window,
+		// 多留位置用于普通回应和结束错误
+		items: make(chan streamItem, window+2),
+	}
+
+	// 结束标记、错误回应、Session 关闭，或服务器以普通回应结束
+	self.req = createRequest(ses, reqMsg, func(raw interface{}) {
+		self.stopCtx()
+
+		if err, ok := raw.(error); ok {
+			self.items <- streamItem{err: err}
+			return
+		}
+
+		// 普通回应作为最后一个数据帧
+		self.items <- streamItem{msg: raw}
+		self.items <- streamItem{err: io.EOF}
+	})
+
+	self.req.window = uint32(window)
+	self.req.deadline, _ = ctx.Deadline()
+	self.req.onFrame = self.onFrame
+
+	// ctx 结束时取消流
+	self.stopCtx = context.AfterFunc(ctx, self.Close)
+
+	// 发送 RPC 请求
+	self.req.Send(ses, reqMsg)
+
+	return self, nil
+}
+
+// onFrame 接收到数据帧
+// 在网络接收的 goroutine 中调用
+func (self *ClientStream) onFrame(msg interface{}) {
+	// 服务器没有遵守接收窗口
+	if len(self.items) >= self.window {
+		if getRequest(self.req.id) != nil {
+			self.stopCtx()
+			self.ses.Send(&RemoteStreamCtrl{CallID: self.req.id, Cancel: true})
+			self.items <- streamItem{err: ErrStreamOverflow}
+		}
+
+		return
+	}
+
+	self.items <- streamItem{msg: msg}
+}
+
+// Recv 接收下一个数据帧
+// 返回数据帧消息和错误
+// 流正常结束时返回 io.EOF，服务器回应错误时返回 *RemoteError，ctx 结束时返回 ctx.Err()
+// 此方法会阻塞当前 goroutine，直到收到数据帧或流结束
+func (self *ClientStream) Recv() (interface{}, error) {
+	if self.err != nil {
+		return nil, self.err
+	}
+
+	select {
+	case item := <-self.items:
+		if item.err != nil {
+			self.err = item.err
+			return nil, item.err
+		}
+
+		self.grant()
+
+		return item.msg, nil
+	case <-self.ctx.Done():
+		self.Close()
+		self.err = self.ctx.Err()
+		return nil, self.err
+	}
+}
+
+// All 返回遍历所有数据帧的迭代器
+// 流正常结束时迭代结束，出错时最后一次迭代返回错误
+// 提前结束迭代时会关闭流
+//
+// 使用示例:
+//
+//	for msg, err := range stream.All() {
+//		if err != nil {
+//			break
+//		}
+//	}
+func (self *ClientStream) All() iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		for {
+			msg, err := self.Recv()
+			if err == io.EOF {
+				return
+			}
+
+			if !yield(msg, err) {
+				self.Close()
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close 关闭流
+// 流还未结束时通知服务器取消，之后 Recv 返回 ErrStreamClosed
+// 可以在任意 goroutine 中调用
+func (self *ClientStream) Close() {
+	if getRequest(self.req.id) == nil {
+		return
+	}
+
+	self.stopCtx()
+	self.ses.Send(&RemoteStreamCtrl{CallID: self.req.id, Cancel: true})
+	self.items <- streamItem{err: ErrStreamClosed}
+}
+
+// grant 读取数据帧后补充服务器的发送窗口
+// 读取的数量达到窗口的一半时补充一次，减少控制消息的数量
+func (self *ClientStream) grant() {
+	self.consumed++
+
+	if self.consumed*2 < self.window {
+		return
+	}
+
+	self.ses.Send(&RemoteStreamCtrl{
+		CallID:  self.req.id,
+		Credits: uint32(self.consumed),
+	})
+
+	self.consumed = 0
+}
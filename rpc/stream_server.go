@@ -0,0 +1,241 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
+)
+
+var (
+	// streamGuard 保护 streamBySession
+	streamGuard sync.Mutex
+
+	// streamBySession 按 Session 和调用 ID 存储服务器端未结束的流
+	// 收到客户端的流控制消息时，用于找到对应的流
+	streamBySession = map[cellnet.Session]map[int64]*ServerStream{}
+)
+
+// ServerStream 服务器端的流式响应
+// 客户端通过 CallStream 发起请求时，服务器端通过 RecvMsgEvent.Stream 获得
+// 按客户端的接收窗口发送数据帧，超出窗口的数据帧缓存在服务器端，客户端补充窗口后继续发送
+// 所有方法都可以在任意 goroutine 中调用
+type ServerStream struct {
+	// ev 对应的请求事件
+	ev *RecvMsgEvent
+
+	// ctx 流的上下文，客户端取消、流结束或 Session 关闭时取消
+	ctx context.Context
+
+	// cancel 取消流的上下文
+	cancel context.CancelFunc
+
+	// guard 保护以下字段
+	guard sync.Mutex
+
+	// credits 客户端剩余的接收窗口
+	credits uint32
+
+	// pending 超出接收窗口，等待发送的数据帧
+	pending []*RemoteStreamFrame
+
+	// end 等待发送的结束标记，调用 End 后设置
+	end *RemoteCallACK
+
+	// closed 流已经结束或被取消
+	closed bool
+}
+
+// Send 发送一个数据帧
+// msg: 数据帧消息对象
+// 客户端的接收窗口已满时缓存数据帧，不会阻塞
+// 流已经结束或被取消时返回 ErrStreamClosed
+func (self *ServerStream) Send(msg interface{}) error {
+	data, meta, err := codec.EncodeMessage(msg, nil)
+	if err != nil {
+		return err
+	}
+
+	frame := &RemoteStreamFrame{
+		MsgID:  uint32(meta.ID),
+		Data:   data,
+		CallID: self.ev.callid,
+	}
+
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if self.closed || self.end != nil {
+		return ErrStreamClosed
+	}
+
+	self.pending = append(self.pending, frame)
+	self.flush()
+
+	return nil
+}
+
+// End 正常结束流
+// 缓存的数据帧全部发送后，向客户端发送结束标记，客户端收到 io.EOF
+func (self *ServerStream) End() {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if self.closed || self.end != nil {
+		return
+	}
+
+	// 结束标记是没有用户消息且没有错误码的 RemoteCallACK
+	self.end = &RemoteCallACK{CallID: self.ev.callid}
+	self.flush()
+}
+
+// EndError 以错误结束流
+// code: 错误码，为 0 时使用 ErrCodeUnknown
+// message: 错误描述
+// detail: 错误的详细信息消息，可以为 nil
+// 立即发送错误回应，缓存的数据帧被丢弃，客户端收到 *RemoteError
+func (self *ServerStream) EndError(code int32, message string, detail interface{}) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if self.closed {
+		return
+	}
+
+	self.ev.ses.Send(newErrorACK(self.ev.callid, code, message, detail))
+	self.close()
+}
+
+// Context 获取流的上下文
+// 客户端取消、流结束、超过调用方的截止时间或 Session 关闭时，上下文被取消
+func (self *ServerStream) Context() context.Context {
+	return self.ctx
+}
+
+// Pending 获取超出接收窗口，等待发送的数据帧数量
+// 可以据此暂停生产数据帧，避免服务器端缓存过多
+func (self *ServerStream) Pending() int {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	return len(self.pending)
+}
+
+// onCtrl 处理客户端的流控制消息
+// ctrl: 流控制消息，补充接收窗口或取消流
+func (self *ServerStream) onCtrl(ctrl *RemoteStreamCtrl) {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if self.closed {
+		return
+	}
+
+	if ctrl.Cancel {
+		self.close()
+		return
+	}
+
+	self.credits += ctrl.Credits
+	self.flush()
+}
+
+// flush 在接收窗口内发送缓存的数据帧，全部发送后发送结束标记
+// 调用时需持有 guard
+func (self *ServerStream) flush() {
+	for len(self.pending) > 0 && self.credits > 0 {
+		self.ev.ses.Send(self.pending[0])
+		self.pending[0] = nil
+		self.pending = self.pending[1:]
+		self.credits--
+	}
+
+	if len(self.pending) == 0 && self.end != nil {
+		self.ev.ses.Send(self.end)
+		self.close()
+	}
+}
+
+// close 结束流，释放资源
+// 调用时需持有 guard
+func (self *ServerStream) close() {
+	self.closed = true
+	self.pending = nil
+	self.cancel()
+	self.ev.release()
+
+	removeStream(self.ev.ses, self.ev.callid)
+}
+
+// detach 请求已经以普通回应结束，不再作为流使用
+func (self *ServerStream) detach() {
+	self.guard.Lock()
+	defer self.guard.Unlock()
+
+	if !self.closed {
+		self.close()
+	}
+}
+
+// newServerStream 为流式请求创建服务器端的流
+// ev: 请求事件
+// window: 客户端的初始接收窗口
+func newServerStream(ev *RecvMsgEvent, window uint32) *ServerStream {
+	self := &ServerStream{
+		ev:      ev,
+		credits: window,
+	}
+
+	self.ctx, self.cancel = context.WithCancel(ev.Context())
+
+	streamGuard.Lock()
+
+	streamByID := streamBySession[ev.ses]
+	if streamByID == nil {
+		streamByID = map[int64]*ServerStream{}
+		streamBySession[ev.ses] = streamByID
+	}
+
+	streamByID[ev.callid] = self
+
+	streamGuard.Unlock()
+
+	return self
+}
+
+// getStream 根据 Session 和调用 ID 获取未结束的流
+func getStream(ses cellnet.Session, callid int64) *ServerStream {
+	streamGuard.Lock()
+	defer streamGuard.Unlock()
+
+	return streamBySession[ses][callid]
+}
+
+// removeStream 移除已经结束的流
+func removeStream(ses cellnet.Session, callid int64) {
+	streamGuard.Lock()
+	defer streamGuard.Unlock()
+
+	if streamByID := streamBySession[ses]; streamByID != nil {
+		delete(streamByID, callid)
+
+		if len(streamByID) == 0 {
+			delete(streamBySession, ses)
+		}
+	}
+}
+
+// closeSessionStreams 结束 Session 上所有未结束的流
+// ses: 已关闭的 Session
+func closeSessionStreams(ses cellnet.Session) {
+	streamGuard.Lock()
+	streamByID := streamBySession[ses]
+	delete(streamBySession, ses)
+	streamGuard.Unlock()
+
+	for _, stream := range streamByID {
+		stream.detach()
+	}
+}
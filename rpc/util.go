@@ -22,7 +22,7 @@ type RPCSessionGetter interface {
 }
 
 // getPeerSession 从 Peer 获取 RPC 使用的 Session
-// ud: Session、RPCSessionGetter 或 TCPConnector
+// ud: Session、RPCSessionGetter、TCPConnector 或 UDPConnector
 // 返回对应的 Session 和错误信息
 // 如果类型不支持或 Session 为空，返回错误
 func getPeerSession(ud interface{}) (ses cellnet.Session, err error) {
@@ -41,6 +41,9 @@ func getPeerSession(ud interface{}) (ses cellnet.Session, err error) {
 	case cellnet.TCPConnector:
 		// TCPConnector，获取其 Session
 		ses = i.Session()
+	case cellnet.UDPConnector:
+		// UDP 和 KCP 连接器，获取其 Session
+		ses = i.Session()
 	default:
 		// 不支持的类型
 		err = ErrInvalidPeerSession
@@ -54,3 +57,29 @@ func getPeerSession(ud interface{}) (ses cellnet.Session, err error) {
 
 	return
 }
+
+// peerQueue 获取 Peer 或连接池的事件队列
+// sesOrPeer: 提供 Queue() 方法的 Peer 或 ClientPool
+// 没有事件队列时返回 nil
+func peerQueue(sesOrPeer interface{}) cellnet.EventQueue {
+	if queueGetter, ok := sesOrPeer.(interface {
+		Queue() cellnet.EventQueue
+	}); ok {
+		return queueGetter.Queue()
+	}
+
+	return nil
+}
+
+// queuedCall 在 Session 对应 Peer 的事件队列中调用回调
+// sesOrPeer: 请求时传入的 Session 或 Peer
+// ses: 请求使用的 Session，获取 Session 失败时为 nil
+// ses 为 nil 时在 sesOrPeer 的事件队列中调用，没有事件队列时直接调用，保证回调不会丢失
+func queuedCall(sesOrPeer interface{}, ses cellnet.Session, callback func()) {
+	if ses != nil {
+		cellnet.SessionQueuedCall(ses, callback)
+		return
+	}
+
+	cellnet.QueuedCall(peerQueue(sesOrPeer), callback)
+}
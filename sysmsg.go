@@ -1,6 +1,9 @@
 package cellnet
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // SessionInit 表示会话初始化事件
 // 在 Session 创建时触发，用于初始化会话
@@ -25,6 +28,26 @@ type SessionConnected struct {
 type SessionConnectError struct {
 }
 
+// SessionReconnecting 表示连接器准备重连的事件
+// 连接失败或连接断开后，按重连策略等待之前触发
+type SessionReconnecting struct {
+	// Attempt 重连次数，从 1 开始，连接成功后重新计数
+	Attempt int32
+
+	// Address 本次重连使用的地址
+	Address string
+
+	// Delay 重连前等待的时间
+	Delay time.Duration
+}
+
+// SessionReconnectGiveUp 表示连接器放弃重连的事件
+// 重连策略返回放弃时触发，之后会触发 SessionConnectError 并停止连接
+type SessionReconnectGiveUp struct {
+	// Attempts 放弃前已经尝试的重连次数
+	Attempts int32
+}
+
 // CloseReason 表示连接关闭的原因
 type CloseReason int32
 
@@ -65,22 +88,26 @@ type SessionCloseNotify struct {
 }
 
 // String 方法实现 fmt.Stringer 接口，用于格式化输出
-func (self *SessionInit) String() string         { return fmt.Sprintf("%+v", *self) }
-func (self *SessionAccepted) String() string     { return fmt.Sprintf("%+v", *self) }
-func (self *SessionConnected) String() string    { return fmt.Sprintf("%+v", *self) }
-func (self *SessionConnectError) String() string { return fmt.Sprintf("%+v", *self) }
-func (self *SessionClosed) String() string       { return fmt.Sprintf("%+v", *self) }
-func (self *SessionCloseNotify) String() string  { return fmt.Sprintf("%+v", *self) }
+func (self *SessionInit) String() string            { return fmt.Sprintf("%+v", *self) }
+func (self *SessionAccepted) String() string        { return fmt.Sprintf("%+v", *self) }
+func (self *SessionConnected) String() string       { return fmt.Sprintf("%+v", *self) }
+func (self *SessionConnectError) String() string    { return fmt.Sprintf("%+v", *self) }
+func (self *SessionReconnecting) String() string    { return fmt.Sprintf("%+v", *self) }
+func (self *SessionReconnectGiveUp) String() string { return fmt.Sprintf("%+v", *self) }
+func (self *SessionClosed) String() string          { return fmt.Sprintf("%+v", *self) }
+func (self *SessionCloseNotify) String() string     { return fmt.Sprintf("%+v", *self) }
 
 // SystemMessage 方法标记这些消息为系统消息
 // 系统消息是框架内部使用的消息，不会通过正常的消息注册流程
 // 可以通过类型断言 SystemMessageIdentifier 来判断是否为系统消息
-func (self *SessionInit) SystemMessage()         {}
-func (self *SessionAccepted) SystemMessage()     {}
-func (self *SessionConnected) SystemMessage()    {}
-func (self *SessionConnectError) SystemMessage() {}
-func (self *SessionClosed) SystemMessage()       {}
-func (self *SessionCloseNotify) SystemMessage()  {}
+func (self *SessionInit) SystemMessage()            {}
+func (self *SessionAccepted) SystemMessage()        {}
+func (self *SessionConnected) SystemMessage()       {}
+func (self *SessionConnectError) SystemMessage()    {}
+func (self *SessionReconnecting) SystemMessage()    {}
+func (self *SessionReconnectGiveUp) SystemMessage() {}
+func (self *SessionClosed) SystemMessage()          {}
+func (self *SessionCloseNotify) SystemMessage()     {}
 
 // SystemMessageIdentifier 是系统消息的标识接口
 // 所有系统消息都实现了此接口
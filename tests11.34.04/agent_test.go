@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/agent"
+	"github.com/bobwong89757/cellnet/peer"
+	"github.com/bobwong89757/cellnet/proc"
+	"github.com/bobwong89757/cellnet/relay"
+)
+
+const (
+	agentBackend_Address  = "127.0.0.1:16810"
+	agentFrontend_Address = "127.0.0.1:16811"
+)
+
+func TestAgent(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	// 按消息元信息的上下文路由到 echo 服务
+	cellnet.MessageMetaByMsg(&TestEchoACK{}).SetContext("service", "echo")
+
+	// 后端服务
+	backendQueue := cellnet.NewEventQueue()
+	backend := peer.NewGenericPeer("tcp.Acceptor", "backend", agentBackend_Address, backendQueue)
+
+	var clientID int64
+
+	proc.BindProcessorHandler(backend, "tcp.ltv", func(ev cellnet.Event) {
+		switch ev := ev.(type) {
+		case *relay.RecvMsgEvent:
+			if _, ok := ev.Message().(cellnet.SystemMessageIdentifier); ok {
+				t.Error("agent system message routed to backend", ev.Message())
+				return
+			}
+
+			clientID = agent.ClientID(ev)
+
+			ev.Reply(&TestEchoACK{Msg: "reply"})
+			agent.Broadcast(ev.Ses, &TestEchoACK{Msg: "all"})
+		default:
+			if msg, ok := ev.Message().(*agent.ClientClosedACK); ok {
+				if msg.ID != clientID {
+					t.Error("agent client closed id mismatch", msg.ID, clientID)
+				}
+
+				signal.Done(3)
+			}
+		}
+	})
+
+	backend.Start()
+	backendQueue.StartLoop()
+
+	// 网关
+	agentQueue := cellnet.NewEventQueue()
+	frontend := peer.NewGenericPeer("tcp.Acceptor", "agent", agentFrontend_Address, agentQueue)
+	backendConnector := peer.NewGenericPeer("tcp.Connector", "agent.backend", agentBackend_Address, agentQueue)
+
+	gate := agent.NewAgent(frontend)
+	gate.RouteIDRange(1, 2, "none")
+
+	// 范围覆盖系统事件的消息 ID 时，系统事件仍然交给用户回调
+	acceptedID := cellnet.MessageMetaByMsg(&cellnet.SessionAccepted{}).ID
+	gate.RouteIDRange(acceptedID, acceptedID, "echo")
+	gate.RouteByContext("service")
+	gate.AddBackend("echo", backendConnector)
+
+	proc.BindProcessorHandler(frontend, "tcp.ltv", gate.ClientCallback(func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *TestEchoACK:
+			t.Error("agent routed message reached user callback")
+		case *cellnet.SessionAccepted:
+			signal.Done(4)
+		}
+	}))
+
+	backendReady := make(chan struct{})
+
+	proc.BindProcessorHandler(backendConnector, "tcp.ltv", func(ev cellnet.Event) {
+		switch ev.(type) {
+		case *relay.RecvMsgEvent:
+			t.Error("agent client message reached backend connector callback")
+		default:
+			if _, ok := ev.Message().(*cellnet.SessionConnected); ok {
+				close(backendReady)
+			}
+		}
+	})
+
+	frontend.Start()
+	backendConnector.Start()
+	agentQueue.StartLoop()
+
+	<-backendReady
+
+	// 客户端
+	clientQueue := cellnet.NewEventQueue()
+	client := peer.NewGenericPeer("tcp.Connector", "client", agentFrontend_Address, clientQueue)
+
+	var recvReply bool
+
+	proc.BindProcessorHandler(client, "tcp.ltv", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ev.Session().Send(&TestEchoACK{Msg: "hello"})
+		case *TestEchoACK:
+			switch msg.Msg {
+			case "reply":
+				recvReply = true
+				signal.Done(1)
+			case "all":
+				if !recvReply {
+					t.Error("agent broadcast arrived before reply")
+				}
+
+				signal.Done(2)
+
+				// 客户端断开，网关通知后端
+				ev.Session().Close()
+			}
+		}
+	})
+
+	client.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("agent not work", 1, 2, 3, 4)
+
+	client.Stop()
+	backendConnector.Stop()
+	frontend.Stop()
+	backend.Stop()
+}
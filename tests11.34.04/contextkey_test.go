@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+)
+
+func TestContextKey(t *testing.T) {
+
+	var (
+		nameKey  = cellnet.NewContextKey[string]("name")
+		countKey = cellnet.NewContextKey[*int]("count")
+	)
+
+	cs := peer.NewPeer("tcp.Acceptor").(cellnet.ContextSet)
+
+	nameKey.Set(cs, "hello")
+	if v, ok := nameKey.Get(cs); !ok || v != "hello" {
+		t.FailNow()
+	}
+
+	// 同名的不同键互不干扰
+	if _, ok := cellnet.NewContextKey[string]("name").Get(cs); ok {
+		t.FailNow()
+	}
+
+	nameKey.Delete(cs)
+	if nameKey.Value(cs, "default") != "default" {
+		t.FailNow()
+	}
+
+	first := countKey.GetOrInit(cs, func() *int { return new(int) })
+	*first = 5
+	if second := countKey.GetOrInit(cs, func() *int { return new(int) }); *second != 5 {
+		t.FailNow()
+	}
+
+	// 超过列表容量后改用映射表存储
+	for i := 0; i < 100; i++ {
+		cs.SetContext(i, i)
+	}
+
+	for i := 0; i < 100; i++ {
+		var v int
+		if !cs.FetchContext(i, &v) || v != i {
+			t.FailNow()
+		}
+	}
+
+	cs.DeleteContext(50)
+	if _, ok := cs.GetContext(50); ok {
+		t.FailNow()
+	}
+
+	if v, ok := countKey.Get(cs); !ok || *v != 5 {
+		t.FailNow()
+	}
+}
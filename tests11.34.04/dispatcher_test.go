@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/proc"
+)
+
+func TestDispatcherMiddleware(t *testing.T) {
+
+	dispatcher := proc.NewMessageDispatcher()
+	msgName := cellnet.MessageMetaByMsg(&TestEchoACK{}).FullName()
+
+	var trace []string
+
+	record := func(name string) proc.Middleware {
+		return func(next cellnet.EventCallback) cellnet.EventCallback {
+			return func(ev cellnet.Event) {
+				trace = append(trace, name)
+				next(ev)
+			}
+		}
+	}
+
+	var recovered error
+	var elapsed time.Duration
+
+	dispatcher.Use(proc.Recovery(func(ev cellnet.Event, err error) {
+		recovered = err
+	}), proc.Timing(func(ev cellnet.Event, d time.Duration) {
+		elapsed = d
+	}), record("dispatcher"))
+
+	// 鉴权中间件，内容为 deny 时中断处理
+	dispatcher.UseMessage(msgName, record("message"), func(next cellnet.EventCallback) cellnet.EventCallback {
+		return func(ev cellnet.Event) {
+			if ev.Message().(*TestEchoACK).Msg == "deny" {
+				return
+			}
+
+			next(ev)
+		}
+	})
+
+	dispatcher.RegisterMessage(msgName, func(ev cellnet.Event) {
+		trace = append(trace, "handler1")
+
+		if ev.Message().(*TestEchoACK).Msg == "panic" {
+			panic(errors.New("handler1 failed"))
+		}
+	})
+
+	dispatcher.RegisterMessage(msgName, func(ev cellnet.Event) {
+		trace = append(trace, "handler2")
+
+		if ev.Message().(*TestEchoACK).Msg == "panic" {
+			panic("handler2 failed")
+		}
+	})
+
+	dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{Msg: "hello"}})
+
+	if !reflect.DeepEqual(trace, []string{"dispatcher", "message", "handler1", "handler2"}) {
+		t.Error("middleware order mismatch", trace)
+	}
+
+	if elapsed <= 0 {
+		t.Error("timing middleware not called")
+	}
+
+	// 中断处理
+	trace = nil
+	dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{Msg: "deny"}})
+
+	if !reflect.DeepEqual(trace, []string{"dispatcher", "message"}) {
+		t.Error("middleware short-circuit mismatch", trace)
+	}
+
+	// 处理函数 panic 时，之后的处理函数继续调用，中间件获得所有错误
+	trace = nil
+	dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{Msg: "panic"}})
+
+	if !reflect.DeepEqual(trace, []string{"dispatcher", "message", "handler1", "handler2"}) {
+		t.Error("handler after panic not called", trace)
+	}
+
+	var handlerErr *proc.HandlerError
+	if !errors.As(recovered, &handlerErr) || len(handlerErr.Panics) != 2 {
+		t.Fatal("handler errors not recovered", recovered)
+	}
+
+	if handlerErr.Panics[1].Value != "handler2 failed" || len(handlerErr.Panics[0].Stack) == 0 {
+		t.Error("handler error mismatch", handlerErr)
+	}
+}
+
+func TestDispatcherPanicWithoutMiddleware(t *testing.T) {
+
+	dispatcher := proc.NewMessageDispatcher()
+	msgName := cellnet.MessageMetaByMsg(&TestEchoACK{}).FullName()
+
+	var handler2Called bool
+
+	dispatcher.RegisterMessage(msgName, func(ev cellnet.Event) {
+		panic("handler1 failed")
+	})
+
+	dispatcher.RegisterMessage(msgName, func(ev cellnet.Event) {
+		handler2Called = true
+	})
+
+	// 没有中间件时，第一个 panic 原样向外传递，之后的处理函数不再调用
+	func() {
+		defer func() {
+			if v := recover(); v != "handler1 failed" {
+				t.Error("handler panic changed", v)
+			}
+		}()
+
+		dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{}})
+	}()
+
+	if handler2Called {
+		t.Error("handler after panic called without middleware")
+	}
+}
+
+func TestDispatcherHandle(t *testing.T) {
+
+	dispatcher := proc.NewMessageDispatcher()
+	msgName := cellnet.MessageMetaByMsg(&TestEchoACK{}).FullName()
+
+	var typed, fallback []string
+
+	reg := proc.Handle(dispatcher, func(ev cellnet.Event, msg *TestEchoACK) {
+		typed = append(typed, msg.Msg)
+	})
+
+	dispatcher.SetFallback(func(ev cellnet.Event) {
+		fallback = append(fallback, cellnet.MessageToName(ev.Message()))
+	})
+
+	dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{Msg: "typed"}})
+	dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &cellnet.SessionAccepted{}})
+	dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &cellnet.SessionClosed{}})
+
+	if !dispatcher.Exists(msgName) {
+		t.Error("typed handler not registered")
+	}
+
+	// 注销后交给兜底处理函数，重复注销无影响
+	reg.Remove()
+	reg.Remove()
+
+	if dispatcher.Exists(msgName) {
+		t.Error("typed handler not removed")
+	}
+
+	dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{Msg: "removed"}})
+
+	if !reflect.DeepEqual(typed, []string{"typed"}) {
+		t.Error("typed handler mismatch", typed)
+	}
+
+	// 系统事件不交给兜底处理函数
+	if !reflect.DeepEqual(fallback, []string{"TestEchoACK"}) {
+		t.Error("fallback handler mismatch", fallback)
+	}
+
+	// 取消兜底处理函数
+	dispatcher.SetFallback(nil)
+	dispatcher.OnEvent(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{Msg: "dropped"}})
+
+	if len(fallback) != 1 {
+		t.Error("fallback handler not removed", fallback)
+	}
+}
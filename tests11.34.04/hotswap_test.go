@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+	"github.com/bobwong89757/cellnet/proc"
+)
+
+const hotSwap_Address = "127.0.0.1:16814"
+
+func TestSwapMessageDispatcher(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", hotSwap_Address, serverQueue)
+
+	oldDispatcher := proc.NewMessageDispatcherBindPeer(acceptor, "tcp.ltv")
+
+	newDispatcher := proc.NewMessageDispatcher()
+	proc.Handle(newDispatcher, func(ev cellnet.Event, msg *TestEchoACK) {
+		ev.Session().Send(&TestEchoACK{Msg: "new"})
+	})
+
+	// 收到第一个消息后替换派发器，之后的消息由新的派发器处理
+	proc.Handle(oldDispatcher, func(ev cellnet.Event, msg *TestEchoACK) {
+		if proc.SwapMessageDispatcher(acceptor, newDispatcher) != oldDispatcher {
+			t.Error("swap returned wrong dispatcher")
+		}
+
+		ev.Session().Send(&TestEchoACK{Msg: "old"})
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", hotSwap_Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ev.Session().Send(&TestEchoACK{Msg: "hello"})
+		case *TestEchoACK:
+			switch msg.Msg {
+			case "old":
+				signal.Done(1)
+				ev.Session().Send(&TestEchoACK{Msg: "hello"})
+			case "new":
+				signal.Done(2)
+			}
+		}
+	})
+
+	connector.(cellnet.TCPConnector).SetReconnectDuration(0)
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("dispatcher not swapped", 1, 2)
+
+	if proc.PeerMessageDispatcher(acceptor) != newDispatcher {
+		t.Error("peer dispatcher mismatch")
+	}
+
+	connector.Stop()
+	acceptor.Stop()
+}
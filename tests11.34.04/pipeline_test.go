@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+	"github.com/bobwong89757/cellnet/proc"
+	"github.com/bobwong89757/cellnet/proc/compress"
+	"github.com/bobwong89757/cellnet/relay"
+	"github.com/bobwong89757/cellnet/reliable"
+	"github.com/bobwong89757/cellnet/rpc"
+)
+
+const (
+	pipeline_Address         = "127.0.0.1:16813"
+	pipelineReliable_Address = "127.0.0.1:9307"
+)
+
+// pipelineSpy 统计内层传输器收发的压缩消息
+type pipelineSpy struct {
+	cellnet.MessageTransmitter
+}
+
+var (
+	pipelineCompressed int64
+
+	// pipelineReencode 交给内层传输器、需要再次编码的消息数量
+	pipelineReencode int64
+)
+
+func (self pipelineSpy) OnSendMessage(ses cellnet.Session, msg interface{}) error {
+	switch msg.(type) {
+	case *cellnet.RawPacket, *compress.CompressedACK:
+	default:
+		atomic.AddInt64(&pipelineReencode, 1)
+	}
+
+	return self.MessageTransmitter.OnSendMessage(ses, msg)
+}
+
+func (self pipelineSpy) OnRecvMessage(ses cellnet.Session) (msg interface{}, err error) {
+	msg, err = self.MessageTransmitter.OnRecvMessage(ses)
+	if _, ok := msg.(*compress.CompressedACK); ok {
+		atomic.AddInt64(&pipelineCompressed, 1)
+	}
+
+	return
+}
+
+func init() {
+	proc.RegisterTransmitterWrapper("test.spy", func(inner cellnet.MessageTransmitter) cellnet.MessageTransmitter {
+		return pipelineSpy{inner}
+	})
+
+	// 排在 compress 之前，看到的是压缩后的消息
+	proc.RegisterPipeline("test.pipeline", "tcp.ltv", "test.spy", "compress", "rpc", "relay", "msglog")
+}
+
+func TestProcessorPipeline(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", pipeline_Address, serverQueue)
+
+	proc.BindProcessorHandler(acceptor, "test.pipeline", func(ev cellnet.Event) {
+		msg, ok := ev.Message().(*TestEchoACK)
+		if !ok {
+			return
+		}
+
+		switch ev := ev.(type) {
+		case *rpc.RecvMsgEvent:
+			ev.Reply(msg)
+		case *relay.RecvMsgEvent:
+			ev.Reply(msg)
+		default:
+			ev.Session().Send(msg)
+		}
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", pipeline_Address, clientQueue)
+
+	big := strings.Repeat("pipeline", 100)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv + test.spy + compress + rpc + relay + msglog", func(ev cellnet.Event) {
+		switch ev := ev.(type) {
+		case *relay.RecvMsgEvent:
+			if ev.Msg.(*TestEchoACK).Msg == "relay" {
+				signal.Done(3)
+			}
+		default:
+			switch msg := ev.Message().(type) {
+			case *cellnet.SessionConnected:
+				ev.Session().Send(&TestEchoACK{Msg: big})
+
+				rpc.Call(ev.Session(), &TestEchoACK{Msg: big, Value: 1}, time.Second, func(raw interface{}) {
+					if ack, ok := raw.(*TestEchoACK); ok && ack.Value == 1 && ack.Msg == big {
+						signal.Done(2)
+					} else {
+						t.Error("pipeline rpc failed", raw)
+					}
+				})
+
+				relay.Relay(ev.Session(), &TestEchoACK{Msg: "relay"})
+			case *TestEchoACK:
+				if msg.Msg == big {
+					signal.Done(1)
+				}
+			}
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("processor pipeline not work", 1, 2, 3)
+
+	// 普通消息和 rpc 的请求、回应都超过压缩的最小大小，双方各收到 2 个压缩消息
+	if n := atomic.LoadInt64(&pipelineCompressed); n < 4 {
+		t.Error("pipeline compress not applied", n)
+	}
+
+	// 小于压缩最小大小的消息使用已编码的数据发送
+	if n := atomic.LoadInt64(&pipelineReencode); n != 0 {
+		t.Error("pipeline message encoded twice", n)
+	}
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+func TestPipelineReliable(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", pipelineReliable_Address, serverQueue)
+
+	// 管线的钩子替换基础处理器的钩子时，可靠会话的钩子保留
+	proc.BindProcessorHandler(acceptor, "tcp.ltv.reliable + rpc + msglog", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *reliable.SessionResumed:
+			signal.Done(2)
+		case *TestEchoACK:
+			switch ev := ev.(type) {
+			case *rpc.RecvMsgEvent:
+				ev.Reply(msg)
+			default:
+				// 断开物理连接，断开期间发送的消息在恢复后送达
+				ses := ev.Session().(*reliable.Session)
+				ses.Physical().Close()
+				ses.Send(&TestEchoACK{Msg: "after resume"})
+			}
+		}
+	}, &reliable.Option{GracePeriod: time.Second * 5})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", pipelineReliable_Address, clientQueue)
+	connector.(cellnet.TCPConnector).SetReconnectDuration(time.Millisecond * 100)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv.reliable + rpc + msglog", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			if _, ok := ev.Session().(*reliable.Session); !ok {
+				t.Error("pipeline reliable session lost", ev.Session())
+			}
+
+			ev.Session().Send(&TestEchoACK{Msg: "hello"})
+		case *TestEchoACK:
+			if msg.Msg != "after resume" {
+				return
+			}
+
+			signal.Done(1)
+
+			rpc.Call(ev.Session(), &TestEchoACK{Msg: "rpc", Value: 3}, time.Second, func(raw interface{}) {
+				if ack, ok := raw.(*TestEchoACK); ok && ack.Value == 3 {
+					signal.Done(3)
+				} else {
+					t.Error("pipeline reliable rpc failed", raw)
+				}
+			})
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("pipeline reliable session not resumed", 1, 2, 3)
+
+	connector.Stop()
+	acceptor.Stop()
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+	"github.com/bobwong89757/cellnet/proc"
+	"testing"
+	"time"
+)
+
+const (
+	reconnect_Address     = "127.0.0.1:9302"
+	reconnect_BadAddress  = "127.0.0.1:9303"
+	reconnect_BadAddress2 = "127.0.0.1:9304"
+)
+
+func TestReconnectFailover(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	queue := cellnet.NewEventQueue()
+
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "reconnect.server", reconnect_Address, queue)
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", nil)
+	acceptor.Start()
+
+	// 主地址无法连接，切换到备用地址
+	connector := peer.NewGenericPeer("tcp.Connector", "reconnect.client", reconnect_BadAddress, queue)
+	connector.(cellnet.Reconnector).SetReconnectPolicy(&peer.BackoffReconnectPolicy{
+		Min:    time.Millisecond * 10,
+		Max:    time.Millisecond * 100,
+		Jitter: 0.5,
+	})
+	connector.(cellnet.Reconnector).SetFailoverAddress(cellnet.FailoverPriority, reconnect_Address)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionReconnecting:
+			if msg.Attempt == 1 && msg.Address == reconnect_Address {
+				signal.Done(1)
+			}
+		case *cellnet.SessionConnected:
+			if connector.(cellnet.TCPConnector).Session().Raw() != nil {
+				signal.Done(2)
+			}
+		}
+	})
+
+	connector.Start()
+	queue.StartLoop()
+
+	signal.WaitAndExpect("not connected to failover address", 1, 2)
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+func TestReconnectGiveUp(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	queue := cellnet.NewEventQueue()
+
+	connector := peer.NewGenericPeer("tcp.Connector", "reconnect.client", reconnect_BadAddress, queue)
+	connector.(cellnet.Reconnector).SetReconnectPolicy(&peer.FixedReconnectPolicy{
+		Interval:    time.Millisecond * 10,
+		MaxAttempts: 2,
+	})
+	connector.(cellnet.Reconnector).SetFailoverAddress(cellnet.FailoverRoundRobin, reconnect_BadAddress2)
+
+	var attempts int32
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionReconnecting:
+			attempts = msg.Attempt
+		case *cellnet.SessionReconnectGiveUp:
+			if msg.Attempts == 2 && attempts == 2 {
+				signal.Done(1)
+			}
+		case *cellnet.SessionConnectError:
+			signal.Done(2)
+		}
+	})
+
+	connector.Start()
+	queue.StartLoop()
+
+	signal.WaitAndExpect("reconnect not given up", 1, 2)
+
+	connector.Stop()
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/codec"
 	"github.com/bobwong89757/cellnet/log"
 	"github.com/bobwong89757/cellnet/peer"
 	"github.com/bobwong89757/cellnet/proc"
@@ -167,3 +168,309 @@ func TestRelay(t *testing.T) {
 	relay_Client.Stop()
 
 }
+
+const relayBatch_Address = "127.0.0.1:16803"
+
+func TestRelayBatch(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	var received []int32
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", relayBatch_Address, serverQueue)
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", func(ev cellnet.Event) {
+		relayEvent, ok := ev.(*relay.RecvMsgEvent)
+		if !ok {
+			return
+		}
+
+		// 批量消息按顺序展开，共享透传数据
+		if relayEvent.PassThroughAsInt64() != 7 || relayEvent.PassThroughAsString() != "batch" {
+			t.Error("relay batch passthrough mismatch")
+		}
+
+		received = append(received, relayEvent.Message().(*TestEchoACK).Value)
+
+		if len(received) == 3 {
+			if !reflect.DeepEqual(received, []int32{1, 2, 3}) {
+				t.Error("relay batch order mismatch", received)
+			}
+
+			// 单个消息的转发保持兼容
+			relayEvent.Reply(&TestEchoACK{Msg: "single", Value: 4})
+		}
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", relayBatch_Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+		switch ev := ev.(type) {
+		case *relay.RecvMsgEvent:
+			if msg, ok := ev.Message().(*TestEchoACK); ok && msg.Value == 4 && ev.PassThroughAsInt64() == 7 {
+				signal.Done(1)
+			}
+		default:
+			if _, ok := ev.Message().(*cellnet.SessionConnected); ok {
+				relay.Relay(ev.Session(),
+					&TestEchoACK{Value: 1},
+					&TestEchoACK{Value: 2},
+					&TestEchoACK{Value: 3},
+					int64(7), "batch")
+			}
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("relay batch not work", 1)
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+// 旧版本发送的 RelayACK 编码
+var legacyRelayACKData = []byte{0x2, 0x3, 0x1, 0x2, 0x3, 0x9, 0xd2, 0x9, 0x12, 0x3, 0x72, 0x61, 0x77, 0x19, 0x7, 0x22, 0x2, 0x8, 0x9, 0x2a, 0x6, 0x6c, 0x65, 0x67, 0x61, 0x63, 0x79}
+
+func TestRelayLegacyFormat(t *testing.T) {
+
+	// 旧版本的 RelayACK 仍然可以解码
+	msg, _, err := codec.DecodeMessage(int(cellnet.MessageMetaByMsg(&relay.RelayACK{}).ID), legacyRelayACKData)
+	if err != nil {
+		t.Fatal("decode legacy RelayACK failed", err)
+	}
+
+	ack := msg.(*relay.RelayACK)
+	if ack.MsgID != 1234 || !reflect.DeepEqual(ack.Msg, []byte{1, 2, 3}) || string(ack.Bytes) != "raw" ||
+		ack.Int64 != 7 || !reflect.DeepEqual(ack.Int64Slice, []int64{8, 9}) || ack.Str != "legacy" {
+		t.Error("legacy RelayACK mismatch", ack)
+	}
+
+	// 单个消息的转发仍然使用 RelayACK，格式与旧版本一致
+	data, _, err := codec.EncodeMessage(&relay.RelayACK{
+		Msg:        []byte{1, 2, 3},
+		MsgID:      1234,
+		Bytes:      []byte("raw"),
+		Int64:      7,
+		Int64Slice: []int64{8, 9},
+		Str:        "legacy",
+	}, nil)
+
+	if err != nil || !reflect.DeepEqual(data, legacyRelayACKData) {
+		t.Error("RelayACK format changed", data, err)
+	}
+}
+
+const relayHeader_Address = "127.0.0.1:16804"
+
+func TestRelayHeader(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", relayHeader_Address, serverQueue)
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", func(ev cellnet.Event) {
+		relayEvent, ok := ev.(*relay.RecvMsgEvent)
+		if !ok {
+			return
+		}
+
+		// nil 头部被忽略
+		if relayEvent.Msg.(*TestEchoACK).Msg == "nil header" {
+			if relayEvent.Header().Len() != 0 {
+				t.Error("relay nil header mismatch", relayEvent.Header().Keys())
+			}
+
+			signal.Done(2)
+			return
+		}
+
+		uid, _ := relayEvent.HeaderInt64("uid")
+		trace, _ := relayEvent.HeaderStr("trace")
+		token, _ := relayEvent.HeaderBytes("token")
+
+		if uid != 10001 || trace != "t-1" || string(token) != "abc" {
+			t.Error("relay header mismatch", uid, trace, token)
+		}
+
+		// 类型不一致时视为不存在
+		if _, ok := relayEvent.HeaderStr("uid"); ok {
+			t.Error("relay header kind mismatch")
+		}
+
+		// 原有的透传数据不受影响
+		if relayEvent.PassThroughAsInt64() != 5 {
+			t.Error("relay passthrough mismatch", relayEvent.PassThroughAsInt64())
+		}
+
+		if !reflect.DeepEqual(relayEvent.Header().Keys(), []string{"token", "trace", "uid"}) {
+			t.Error("relay header keys mismatch", relayEvent.Header().Keys())
+		}
+
+		relayEvent.Reply(&TestEchoACK{Msg: "reply"})
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", relayHeader_Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+		switch ev := ev.(type) {
+		case *relay.RecvMsgEvent:
+			// 回复时保留透传头部
+			if trace, ok := ev.HeaderStr("trace"); ok && trace == "t-1" && ev.PassThroughAsInt64() == 5 {
+				signal.Done(1)
+			}
+		default:
+			if _, ok := ev.Message().(*cellnet.SessionConnected); ok {
+				header := relay.NewHeader().
+					SetInt64("uid", 10001).
+					SetStr("trace", "t-1").
+					SetBytes("token", []byte("abc"))
+
+				relay.Relay(ev.Session(), &TestEchoACK{Msg: "hello"}, int64(5), header)
+
+				var nilHeader *relay.Header
+				if err := relay.Relay(ev.Session(), &TestEchoACK{Msg: "nil header"}, nilHeader); err != nil {
+					t.Error("relay nil header failed", err)
+				}
+			}
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("relay header not work", 1, 2)
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+func TestRelayHeaderZero(t *testing.T) {
+
+	// 零值的头部可以直接设置
+	var header relay.Header
+	header.SetStr("trace", "t-1")
+
+	if v, ok := new(relay.Header).SetInt64("uid", 1).Int64("uid"); !ok || v != 1 {
+		t.Error("new header set failed", v)
+	}
+
+	if v, ok := (&relay.Header{}).SetBytes("token", []byte("abc")).Bytes("token"); !ok || string(v) != "abc" {
+		t.Error("empty header set failed", v)
+	}
+
+	if v, ok := header.Str("trace"); !ok || v != "t-1" || header.Len() != 1 {
+		t.Error("zero header set failed", v)
+	}
+
+	// nil 头部读取时视为空头部
+	var nilHeader *relay.Header
+
+	if _, ok := nilHeader.Int64("uid"); ok {
+		t.Error("nil header int64 exists")
+	}
+
+	if _, ok := nilHeader.Str("trace"); ok {
+		t.Error("nil header str exists")
+	}
+
+	if _, ok := nilHeader.Bytes("token"); ok {
+		t.Error("nil header bytes exists")
+	}
+
+	if nilHeader.Has("uid") || nilHeader.Len() != 0 || len(nilHeader.Keys()) != 0 || nilHeader.Delete("uid") != nil {
+		t.Error("nil header not empty")
+	}
+
+	if clone := nilHeader.Clone().SetStr("trace", "t-2"); clone.Len() != 1 {
+		t.Error("nil header clone failed", clone.Keys())
+	}
+}
+
+const relayBroadcaster_Address = "127.0.0.1:16805"
+
+func TestRelayPeerBroadcaster(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", relayBroadcaster_Address, serverQueue)
+
+	// 广播函数和用户回调都在 serverQueue 中调用，计数不需要加锁
+	var echoCount, allCount int
+
+	// 只关注 TestEchoACK，内容为 stop 时停止处理
+	removeEcho := relay.AddBroadcaster(acceptor, func(ev *relay.RecvMsgEvent) bool {
+		echoCount++
+		return ev.Msg.(*TestEchoACK).Msg != "stop"
+	}, (*TestEchoACK)(nil))
+
+	// 关注所有消息，被之前的广播函数停止的消息不会到达这里
+	relay.AddBroadcaster(acceptor, func(ev *relay.RecvMsgEvent) bool {
+		allCount++
+		return true
+	})
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", func(ev cellnet.Event) {
+		relayEvent, ok := ev.(*relay.RecvMsgEvent)
+		if !ok {
+			return
+		}
+
+		switch relayEvent.Msg.(*TestEchoACK).Msg {
+		case "pass":
+			if echoCount != 2 || allCount != 1 {
+				t.Error("relay broadcaster count mismatch", echoCount, allCount)
+			}
+
+			// 注销后，stop 消息不再被停止
+			removeEcho()
+			relayEvent.Reply(&TestEchoACK{Msg: "pass"})
+		case "stop":
+			if echoCount != 2 || allCount != 2 {
+				t.Error("relay stopped message reached callback", echoCount, allCount)
+			}
+
+			signal.Done(2)
+		}
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", relayBroadcaster_Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+		switch ev := ev.(type) {
+		case *relay.RecvMsgEvent:
+			// 客户端 Peer 没有注册广播函数，回复正常到达
+			signal.Done(1)
+			relay.Relay(ev.Session(), &TestEchoACK{Msg: "stop"})
+		default:
+			if _, ok := ev.Message().(*cellnet.SessionConnected); ok {
+				relay.Relay(ev.Session(), &TestEchoACK{Msg: "stop"})
+				relay.Relay(ev.Session(), &TestEchoACK{Msg: "pass"})
+			}
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("relay peer broadcaster not work", 1, 2)
+
+	connector.Stop()
+	acceptor.Stop()
+}
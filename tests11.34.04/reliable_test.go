@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"errors"
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+	"github.com/bobwong89757/cellnet/proc"
+	"github.com/bobwong89757/cellnet/reliable"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	reliable_Address       = "127.0.0.1:9301"
+	reliableLoss_Address   = "127.0.0.1:9305"
+	reliableExpire_Address = "127.0.0.1:9306"
+)
+
+// reliableDropCount 客户端还需要丢弃的序列号消息数量
+var reliableDropCount int32
+
+// reliableLossy 模拟消息在传输中丢失，丢弃指定数量的序列号消息后断开连接
+type reliableLossy struct {
+	cellnet.MessageTransmitter
+}
+
+func (self reliableLossy) OnRecvMessage(ses cellnet.Session) (msg interface{}, err error) {
+	for {
+		msg, err = self.MessageTransmitter.OnRecvMessage(ses)
+		if _, ok := msg.(*reliable.SeqMsg); !ok || atomic.LoadInt32(&reliableDropCount) <= 0 {
+			return
+		}
+
+		if atomic.AddInt32(&reliableDropCount, -1) == 0 {
+			return nil, errors.New("reliable lossy disconnect")
+		}
+	}
+}
+
+func init() {
+	proc.RegisterTransmitterWrapper("test.lossy", func(inner cellnet.MessageTransmitter) cellnet.MessageTransmitter {
+		return reliableLossy{inner}
+	})
+}
+
+func TestReliableResume(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "reliable.server", reliable_Address, serverQueue)
+
+	var acceptedID int64
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv.reliable", func(ev cellnet.Event) {
+
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionAccepted:
+			acceptedID = ev.Session().ID()
+			acceptor.(peer.SessionManager).JoinGroup("room", ev.Session())
+			ev.Session().Send(&TestEchoACK{Msg: "hello", Value: 1})
+		case *reliable.SessionResumed:
+			// 会话管理器中使用逻辑会话替代物理会话，分组在恢复后保留
+			mgr := acceptor.(peer.SessionManager)
+			if ev.Session().ID() == acceptedID && mgr.GetSession(acceptedID) == ev.Session() && mgr.IsInGroup("room", ev.Session()) {
+				signal.Done(2)
+			}
+		case *TestEchoACK:
+			if msg.Value == 1 {
+				// 断开物理连接，断开期间发送的消息在恢复后送达
+				ses := ev.Session().(*reliable.Session)
+				ses.Physical().Close()
+				ses.Send(&TestEchoACK{Msg: "after resume", Value: 2})
+			}
+		case *cellnet.SessionClosed:
+			// 停止 Peer 时主动关闭
+			if msg.Reason != cellnet.CloseReason_Manual {
+				t.Error("logical session should not close within grace period")
+			}
+		}
+
+	}, &reliable.Option{GracePeriod: time.Second * 5})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "reliable.client", reliable_Address, clientQueue)
+	connector.(cellnet.TCPConnector).SetReconnectDuration(time.Millisecond * 100)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv.reliable", func(ev cellnet.Event) {
+
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			signal.Done(1)
+		case *TestEchoACK:
+			switch msg.Value {
+			case 1:
+				ev.Session().Send(msg)
+			case 2:
+				signal.Done(3)
+			}
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("reliable session not resumed", 1, 2, 3)
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+func TestReliableReplayLoss(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "reliable.server", reliableLoss_Address, serverQueue)
+
+	var serverSes *reliable.Session
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv.reliable", func(ev cellnet.Event) {
+
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionAccepted:
+			serverSes = ev.Session().(*reliable.Session)
+		case *TestEchoACK:
+			if msg.Value == 100 {
+				for i := int32(1); i <= 3; i++ {
+					ev.Session().Send(&TestEchoACK{Value: i})
+				}
+			}
+		}
+
+	}, &reliable.Option{GracePeriod: time.Second * 5})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "reliable.client", reliableLoss_Address, clientQueue)
+	connector.(cellnet.TCPConnector).SetReconnectDuration(time.Millisecond * 100)
+
+	var nextValue int32 = 1
+
+	proc.BindProcessorHandler(connector, "tcp.ltv.reliable + test.lossy", func(ev cellnet.Event) {
+
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			// 服务器发送的消息全部丢失，之后连接断开
+			atomic.StoreInt32(&reliableDropCount, 3)
+			ev.Session().Send(&TestEchoACK{Value: 100})
+		case *reliable.SessionResumed:
+			if connector.(peer.SessionManager).GetSession(ev.Session().ID()) != ev.Session() {
+				t.Error("client logical session not in session manager")
+			}
+		case *TestEchoACK:
+			// 丢失的消息在恢复后按顺序重发
+			if msg.Value != nextValue {
+				t.Error("replay order mismatch", msg.Value, nextValue)
+			}
+
+			nextValue++
+
+			if msg.Value == 3 {
+				signal.Done(1)
+			}
+		}
+
+	}, &reliable.Option{AckDelay: time.Millisecond * 50})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("reliable lost messages not replayed", 1)
+
+	// 消息数量达不到 AckEvery，延迟确认后服务器释放重发缓冲
+	deadline := time.Now().Add(time.Second * 2)
+	for serverSes.Unacked() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if n := serverSes.Unacked(); n != 0 {
+		t.Error("replay buffer not acked", n)
+	}
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+func TestReliableGraceExpire(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	const gracePeriod = time.Millisecond * 200
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "reliable.server", reliableExpire_Address, serverQueue)
+	mgr := acceptor.(peer.SessionManager)
+
+	var (
+		acceptedID int64
+		closedAt   time.Time
+	)
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv.reliable", func(ev cellnet.Event) {
+
+		switch ev.Message().(type) {
+		case *cellnet.SessionAccepted:
+			acceptedID = ev.Session().ID()
+			mgr.JoinGroup("room", ev.Session())
+		case *reliable.SessionResumed:
+			t.Error("client should not resume")
+		case *cellnet.SessionClosed:
+			// 宽限期到期后，逻辑会话从会话管理器和分组中移除
+			if ev.Session().ID() != acceptedID || mgr.GetSession(acceptedID) != nil || mgr.GroupSessionCount("room") != 0 {
+				t.Error("logical session not removed", ev.Session().ID(), acceptedID)
+			}
+
+			if time.Since(closedAt) < gracePeriod {
+				t.Error("logical session closed within grace period", time.Since(closedAt))
+			}
+
+			signal.Done(2)
+		}
+
+	}, &reliable.Option{GracePeriod: gracePeriod})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "reliable.client", reliableExpire_Address, clientQueue)
+	connector.(cellnet.TCPConnector).SetReconnectDuration(0)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv.reliable", func(ev cellnet.Event) {
+
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			// 断开后不再重连
+			closedAt = time.Now()
+			ev.Session().(*reliable.Session).Physical().Close()
+			signal.Done(1)
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("reliable grace period not expired", 1, 2)
+
+	connector.Stop()
+	acceptor.Stop()
+}
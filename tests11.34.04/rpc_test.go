@@ -1,6 +1,9 @@
 package tests
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/bobwong89757/cellnet"
 	"github.com/bobwong89757/cellnet/log"
 	"github.com/bobwong89757/cellnet/peer"
@@ -111,7 +114,6 @@ func typeRPC_OnClientEvent(ev cellnet.Event) {
 
 			copy := i + 1
 
-			// 注意, 这里不能使用CallType, 异步第一次回来后, 就将rpc上下文清楚,导致第二次之后的回调无法触发, 不属于bug
 			rpc.CallSyncType(ev.Session(), &TestEchoACK{
 				Msg:   "type",
 				Value: 1234,
@@ -131,10 +133,14 @@ func typeRPC_OnClientEvent(ev cellnet.Event) {
 }
 
 func rpc_StartClient(eventFunc func(event cellnet.Event)) {
+	rpc_StartClientAt(syncRPC_Address, eventFunc)
+}
+
+func rpc_StartClientAt(address string, eventFunc func(event cellnet.Event)) {
 
 	queue := cellnet.NewEventQueue()
 
-	p := peer.NewGenericPeer("tcp.Connector", "client", syncRPC_Address, queue)
+	p := peer.NewGenericPeer("tcp.Connector", "client", address, queue)
 
 	proc.BindProcessorHandler(p, "tcp.ltv.type", eventFunc)
 
@@ -189,3 +195,826 @@ func init() {
 	})
 
 }
+
+const closeRPC_Address = "127.0.0.1:9202"
+
+func TestRPCSessionClosed(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", closeRPC_Address, serverQueue)
+
+	// 服务器收到请求后不回复，直接断开连接
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *TestEchoACK:
+			ev.Session().Close()
+		}
+	})
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", closeRPC_Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ses := ev.Session()
+
+			go func() {
+				_, err := rpc.CallSync(ses, &TestEchoACK{Msg: "sync"}, time.Second*10)
+				if err == rpc.ErrSessionClosed {
+					signal.Done(1)
+				}
+			}()
+
+			rpc.Call(ses, &TestEchoACK{Msg: "async"}, time.Second*10, func(feedback interface{}) {
+				if feedback == rpc.ErrSessionClosed {
+					signal.Done(2)
+				}
+			})
+
+			if calls := rpc.PendingCalls(ses); len(calls) == 0 || calls[0].MsgName != "TestEchoACK" {
+				t.Error("pending calls not listed", calls)
+			}
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("pending rpc not failed on session close", 1, 2)
+
+	if calls := rpc.PendingCalls(nil); len(calls) != 0 {
+		t.Error("pending calls not cleared", calls)
+	}
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+const ctxRPC_Address = "127.0.0.1:9203"
+
+func TestRPCContext(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", ctxRPC_Address, serverQueue)
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *TestEchoACK:
+			rpcEv := ev.(*rpc.RecvMsgEvent)
+
+			if _, ok := rpcEv.Deadline(); !ok && msg.Value != 3 {
+				t.Error("deadline not propagated")
+			}
+
+			// 截止时间由剩余的超时时间计算，不超过调用方的超时时间
+			if deadline, ok := rpcEv.Deadline(); ok && (time.Until(deadline) <= 0 || time.Until(deadline) > time.Second) {
+				t.Error("deadline out of range", time.Until(deadline))
+			}
+
+			switch msg.Value {
+			case 1:
+				rpcEv.Reply(msg)
+
+				// 回复后上下文已取消
+				if ctx := rpcEv.Context(); ctx == nil || ctx.Err() == nil {
+					t.Error("context not canceled after reply")
+				}
+			case 2:
+				// 不回复，等待调用方的截止时间
+				go func() {
+					<-rpcEv.Context().Done()
+					if rpcEv.Context().Err() == context.DeadlineExceeded {
+						signal.Done(3)
+					}
+				}()
+			case 3:
+				// 不回复，等待 Session 关闭
+				go func() {
+					<-rpcEv.Context().Done()
+					if rpcEv.Context().Err() == context.Canceled {
+						signal.Done(5)
+					}
+				}()
+			}
+		}
+	})
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", ctxRPC_Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ses := ev.Session()
+
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+
+				if ack, err := rpc.CallContext(ctx, ses, &TestEchoACK{Msg: "ctx", Value: 1}); err == nil && ack.(*TestEchoACK).Value == 1 {
+					signal.Done(1)
+				}
+
+				ctx2, cancel2 := context.WithTimeout(context.Background(), time.Millisecond*100)
+				defer cancel2()
+
+				if _, err := rpc.CallContext(ctx2, ses, &TestEchoACK{Msg: "ctx", Value: 2}); err == context.DeadlineExceeded {
+					signal.Done(2)
+				}
+
+				ctx3, cancel3 := context.WithCancel(context.Background())
+				rpc.CallContextAsync(ctx3, ses, &TestEchoACK{Msg: "ctx", Value: 3}, func(raw interface{}) {
+					if raw == context.Canceled {
+						signal.Done(4)
+
+						// 关闭连接，服务器端请求的上下文随之取消
+						ses.Close()
+					}
+				})
+				cancel3()
+			}()
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("rpc context not work", 1, 2, 3, 4, 5)
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+const errRPC_Address = "127.0.0.1:9204"
+
+func TestRPCRemoteError(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", errRPC_Address, serverQueue)
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *TestEchoACK:
+			// Call、CallSync 的请求和 CallType 的请求都可以回应错误
+			rpc.ReplyError(ev, 404, "not found", &TestEchoACK{Msg: "detail", Value: msg.Value})
+		}
+	})
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	isExpectedError := func(err interface{}, value int32) bool {
+		remoteErr, ok := err.(*rpc.RemoteError)
+		if !ok || remoteErr.Code != 404 || remoteErr.Message != "not found" {
+			return false
+		}
+
+		detail, ok := remoteErr.Detail.(*TestEchoACK)
+		return ok && detail.Value == value
+	}
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", errRPC_Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv.type", func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ses := ev.Session()
+
+			go func() {
+				if _, err := rpc.CallSync(ses, &TestEchoACK{Value: 1}, time.Second*5); isExpectedError(err, 1) {
+					signal.Done(1)
+				}
+
+				rpc.CallSyncType(ses, &TestEchoACK{Value: 3}, time.Second*5, func(ack *TestEchoACK, err error) {
+					if isExpectedError(err, 3) {
+						signal.Done(3)
+					}
+				})
+			}()
+
+			rpc.Call(ses, &TestEchoACK{Value: 2}, time.Second*5, func(feedback interface{}) {
+				if isExpectedError(feedback, 2) {
+					signal.Done(2)
+				}
+			})
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("remote error not received", 1, 2, 3)
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+func TestInvokeRPC(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	rpc_StartServer()
+
+	rpc_StartClient(func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ses := ev.Session()
+
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+				defer cancel()
+
+				ack, err := rpc.Invoke[TestEchoACK, TestEchoACK](ctx, ses, &TestEchoACK{Msg: "invoke", Value: 1})
+				if err == nil && ack.Msg == "invoke" {
+					signal.Done(1)
+				}
+
+				// 响应类型不一致时返回错误
+				if _, err := rpc.Invoke[TestEchoACK, cellnet.SessionAccepted](ctx, ses, &TestEchoACK{}); errors.Is(err, rpc.ErrAckTypeMismatch) {
+					signal.Done(2)
+				}
+			}()
+
+			rpc.InvokeAsync(context.Background(), ses, &TestEchoACK{Msg: "async", Value: 3}, func(ack *TestEchoACK, err error) {
+				if err == nil && ack.Value == 3 {
+					signal.Done(3)
+				}
+			})
+		}
+	})
+
+	signal.WaitAndExpect("invoke not recv data", 1, 2, 3)
+
+	rpc_Acceptor.Stop()
+}
+
+const typeRPC_ConcurrentAddress = "127.0.0.1:9205"
+
+func TestTypeRPCConcurrent(t *testing.T) {
+
+	const callCount = 100
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", typeRPC_ConcurrentAddress, serverQueue)
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *TestEchoACK:
+			// 同类型的普通消息不会被当作回应
+			ev.Session().Send(&TestEchoACK{Msg: "unsolicited", Value: -1})
+
+			ev.(*rpc.RecvMsgEvent).Reply(&TestEchoACK{Msg: msg.Msg, Value: msg.Value})
+		}
+	})
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", typeRPC_ConcurrentAddress, clientQueue)
+
+	var asyncCount, syncCount int
+
+	proc.BindProcessorHandler(connector, "tcp.ltv.type", func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ses := ev.Session()
+
+			for i := 0; i < callCount; i++ {
+
+				value := int32(i)
+
+				// 异步回调在队列中执行，计数无需加锁
+				rpc.CallType(ses, &TestEchoACK{Msg: "async", Value: value}, time.Second*5, func(ack *TestEchoACK, err error) {
+					if err != nil || ack.Value != value {
+						t.Error("async type rpc mismatch", value, ack, err)
+						return
+					}
+
+					asyncCount++
+					if asyncCount == callCount {
+						signal.Done(1)
+					}
+				})
+
+				go rpc.CallSyncType(ses, &TestEchoACK{Msg: "sync", Value: value}, time.Second*5, func(ack *TestEchoACK, err error) {
+					if err != nil || ack.Value != value {
+						t.Error("sync type rpc mismatch", value, ack, err)
+						return
+					}
+
+					cellnet.SessionQueuedCall(ses, func() {
+						syncCount++
+						if syncCount == callCount {
+							signal.Done(2)
+						}
+					})
+				})
+			}
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("concurrent type rpc not recv data", 1, 2)
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+const serviceRPC_Address = "127.0.0.1:9206"
+
+func TestRPCService(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", serviceRPC_Address, serverQueue)
+
+	var metricsCount int
+
+	svc := rpc.NewServiceBindPeer(acceptor, "tcp.ltv")
+	svc.Use(
+		rpc.RecoveryInterceptor(),
+		rpc.LoggingInterceptor(),
+		rpc.MetricsInterceptor(func(msgName string, cost time.Duration, err error) {
+			metricsCount++
+		}),
+		rpc.AuthInterceptor(func(ev *rpc.RecvMsgEvent) error {
+			if ev.Msg.(*TestEchoACK).Msg == "deny" {
+				return errors.New("denied")
+			}
+
+			return nil
+		}),
+	)
+
+	rpc.Register(svc, func(ev *rpc.RecvMsgEvent, req *TestEchoACK) (interface{}, error) {
+		switch req.Value {
+		case 1:
+			return &TestEchoACK{Msg: req.Msg, Value: req.Value}, nil
+		case 2:
+			panic("handler panic")
+		default:
+			return nil, &rpc.RemoteError{Code: 7, Message: "bad value"}
+		}
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	remoteCode := func(err error) int32 {
+		var remoteErr *rpc.RemoteError
+		if errors.As(err, &remoteErr) {
+			return remoteErr.Code
+		}
+
+		return 0
+	}
+
+	rpc_StartClientAt(serviceRPC_Address, func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ses := ev.Session()
+
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+				defer cancel()
+
+				if ack, err := rpc.Invoke[TestEchoACK, TestEchoACK](ctx, ses, &TestEchoACK{Msg: "ok", Value: 1}); err == nil && ack.Value == 1 {
+					signal.Done(1)
+				}
+
+				if _, err := rpc.Invoke[TestEchoACK, TestEchoACK](ctx, ses, &TestEchoACK{Value: 2}); remoteCode(err) == rpc.ErrCodeInternal {
+					signal.Done(2)
+				}
+
+				if _, err := rpc.Invoke[TestEchoACK, TestEchoACK](ctx, ses, &TestEchoACK{Value: 3}); remoteCode(err) == 7 {
+					signal.Done(3)
+				}
+
+				if _, err := rpc.Invoke[TestEchoACK, TestEchoACK](ctx, ses, &TestEchoACK{Msg: "deny", Value: 1}); remoteCode(err) == rpc.ErrCodeUnauthenticated {
+					signal.Done(4)
+				}
+			}()
+		}
+	})
+
+	signal.WaitAndExpect("rpc service not work", 1, 2, 3, 4)
+
+	// 发生 panic 的请求由外层的 RecoveryInterceptor 处理，不经过统计
+	cellnet.QueuedCall(serverQueue, func() {
+		if metricsCount != 3 {
+			t.Error("metrics count mismatch", metricsCount)
+		}
+		signal.Done(5)
+	})
+
+	signal.WaitAndExpect("rpc service metrics not work", 5)
+
+	acceptor.Stop()
+}
+
+const streamRPC_Address = "127.0.0.1:9207"
+
+func TestRPCStream(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", streamRPC_Address, serverQueue)
+
+	svc := rpc.NewServiceBindPeer(acceptor, "tcp.ltv")
+
+	rpc.Register(svc, func(ev *rpc.RecvMsgEvent, req *TestEchoACK) (interface{}, error) {
+		stream := ev.Stream()
+		if stream == nil {
+			return &TestEchoACK{Msg: "unary", Value: req.Value}, nil
+		}
+
+		switch req.Msg {
+		case "cancel":
+			// 不结束流，等待客户端取消
+			context.AfterFunc(stream.Context(), func() {
+				signal.Done(3)
+			})
+		case "error":
+			stream.Send(&TestEchoACK{Value: 0})
+			stream.EndError(9, "stream failed", nil)
+			return nil, nil
+		}
+
+		for i := int32(0); i < req.Value; i++ {
+			stream.Send(&TestEchoACK{Msg: req.Msg, Value: i})
+		}
+
+		if req.Msg != "cancel" {
+			stream.End()
+		}
+
+		return nil, nil
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	rpc_StartClientAt(streamRPC_Address, func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ses := ev.Session()
+
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+				defer cancel()
+
+				// 数据帧数量超过接收窗口，按顺序全部收到
+				stream, err := rpc.CallStream(ctx, ses, &TestEchoACK{Msg: "list", Value: 20}, 4)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				var count int32
+				for msg, err := range stream.All() {
+					if err != nil {
+						t.Error(err)
+						return
+					}
+
+					if msg.(*TestEchoACK).Value != count {
+						t.Error("stream frame order mismatch", msg.(*TestEchoACK).Value, count)
+						return
+					}
+
+					count++
+				}
+
+				if count == 20 {
+					signal.Done(1)
+				}
+
+				// 服务器以错误结束流
+				stream, _ = rpc.CallStream(ctx, ses, &TestEchoACK{Msg: "error"}, 4)
+				if _, err := stream.Recv(); err != nil {
+					t.Error(err)
+					return
+				}
+
+				var remoteErr *rpc.RemoteError
+				if _, err := stream.Recv(); errors.As(err, &remoteErr) && remoteErr.Code == 9 {
+					signal.Done(2)
+				}
+
+				// 提前结束迭代，服务器的流被取消
+				stream, _ = rpc.CallStream(ctx, ses, &TestEchoACK{Msg: "cancel", Value: 100}, 8)
+				for range stream.All() {
+					break
+				}
+
+				if _, err := stream.Recv(); err != rpc.ErrStreamClosed {
+					t.Error("stream not closed", err)
+				}
+
+				// 普通请求
+				if ack, err := rpc.CallContext(ctx, ses, &TestEchoACK{Value: 5}); err == nil && ack.(*TestEchoACK).Msg == "unary" {
+					signal.Done(4)
+				}
+			}()
+		}
+	})
+
+	signal.WaitAndExpect("rpc stream not work", 1, 2, 3, 4)
+
+	acceptor.Stop()
+}
+
+const (
+	poolRPC_AddressA = "127.0.0.1:9208"
+	poolRPC_AddressB = "127.0.0.1:9209"
+)
+
+func TestRPCClientPool(t *testing.T) {
+
+	startServer := func(name, address string) cellnet.Peer {
+		queue := cellnet.NewEventQueue()
+		acceptor := peer.NewGenericPeer("tcp.Acceptor", name, address, queue)
+
+		svc := rpc.NewServiceBindPeer(acceptor, "tcp.ltv")
+		rpc.Register(svc, func(ev *rpc.RecvMsgEvent, req *TestEchoACK) (interface{}, error) {
+			return &TestEchoACK{Msg: name, Value: req.Value}, nil
+		})
+
+		acceptor.Start()
+		queue.StartLoop()
+
+		return acceptor
+	}
+
+	startClient := func(address string) cellnet.Peer {
+		queue := cellnet.NewEventQueue()
+		connector := peer.NewGenericPeer("tcp.Connector", "client", address, queue)
+		connector.(cellnet.TCPConnector).SetReconnectDuration(time.Millisecond * 100)
+
+		proc.BindProcessorHandler(connector, "tcp.ltv", nil)
+
+		connector.Start()
+		queue.StartLoop()
+
+		return connector
+	}
+
+	waitReady := func(p cellnet.Peer, ready bool) {
+		for i := 0; i < 100; i++ {
+			if p.(cellnet.PeerReadyChecker).IsReady() == ready {
+				return
+			}
+
+			time.Sleep(time.Millisecond * 50)
+		}
+
+		t.Fatal("peer ready state not changed", ready)
+	}
+
+	acceptorA := startServer("a", poolRPC_AddressA)
+	acceptorB := startServer("b", poolRPC_AddressB)
+	defer acceptorA.Stop()
+
+	connectorA := startClient(poolRPC_AddressA)
+	connectorB := startClient(poolRPC_AddressB)
+	defer connectorA.Stop()
+	defer connectorB.Stop()
+
+	waitReady(connectorA, true)
+	waitReady(connectorB, true)
+
+	call := func(sesOrPeer interface{}) string {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+
+		ack, err := rpc.Invoke[TestEchoACK, TestEchoACK](ctx, sesOrPeer, &TestEchoACK{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return ack.Msg
+	}
+
+	// 轮询
+	pool := rpc.NewClientPool(&rpc.RoundRobinStrategy{}, connectorA, connectorB)
+
+	if got := call(pool) + call(pool) + call(pool) + call(pool); got != "abab" {
+		t.Error("round robin mismatch", got)
+	}
+
+	// 空闲时选择靠前的
+	leastPool := rpc.NewClientPool(rpc.LeastPendingStrategy{}, connectorA, connectorB)
+	if got := call(leastPool); got != "a" {
+		t.Error("least pending mismatch", got)
+	}
+
+	// 相同的键总是选中同一个副本
+	hashPool := rpc.NewClientPool(rpc.ConsistentHashStrategy{}, connectorA, connectorB)
+	hashed := map[string]bool{}
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("user%d", i)
+
+		first := call(hashPool.WithKey(key))
+		if call(hashPool.WithKey(key)) != first {
+			t.Error("consistent hash mismatch", key)
+		}
+
+		hashed[first] = true
+	}
+
+	if len(hashed) != 2 {
+		t.Error("consistent hash not spread", hashed)
+	}
+
+	// 未就绪的连接器被跳过
+	acceptorB.Stop()
+	waitReady(connectorB, false)
+
+	if got := call(pool) + call(pool) + call(pool); got != "aaa" {
+		t.Error("unready peer not skipped", got)
+	}
+
+	pool.Remove(connectorA)
+
+	if _, err := rpc.CallContext(context.Background(), pool, &TestEchoACK{}); err != rpc.ErrEmptySession {
+		t.Error("empty pool should fail", err)
+	}
+}
+
+func TestRPCEmptyPool(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	// 未启动的连接器没有就绪，连接池没有可用的 Session
+	queue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", "127.0.0.1:9211", queue)
+	queue.StartLoop()
+	defer queue.StopLoop()
+
+	pool := rpc.NewClientPool(nil, connector)
+
+	rpc.Call(pool, &TestEchoACK{}, time.Second, func(raw interface{}) {
+		if raw == rpc.ErrEmptySession {
+			signal.Done(1)
+		}
+	})
+
+	rpc.CallContextAsync(context.Background(), pool.WithKey("user"), &TestEchoACK{}, func(raw interface{}) {
+		if raw == rpc.ErrEmptySession {
+			signal.Done(2)
+		}
+	})
+
+	rpc.InvokeAsync[TestEchoACK, TestEchoACK](context.Background(), pool, &TestEchoACK{}, func(ack *TestEchoACK, err error) {
+		if ack == nil && err == rpc.ErrEmptySession {
+			signal.Done(3)
+		}
+	})
+
+	signal.WaitAndExpect("empty pool callback lost", 1, 2, 3)
+
+	// 没有连接器时没有事件队列，直接回调
+	var directErr interface{}
+	rpc.Call(rpc.NewClientPool(nil), &TestEchoACK{}, time.Second, func(raw interface{}) {
+		directErr = raw
+	})
+
+	if directErr != rpc.ErrEmptySession {
+		t.Error("empty pool without queue callback mismatch", directErr)
+	}
+}
+
+const retryRPC_Address = "127.0.0.1:9210"
+
+func TestRPCRetry(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", retryRPC_Address, serverQueue)
+
+	var (
+		requestCount int
+		executeCount int
+		keys         = map[string]bool{}
+	)
+
+	svc := rpc.NewServiceBindPeer(acceptor, "tcp.ltv")
+	svc.Use(
+		// 丢弃每次调用第一次尝试的回应，模拟回应在断线时丢失
+		func(ev *rpc.RecvMsgEvent, next rpc.HandlerFunc) (interface{}, error) {
+			requestCount++
+
+			ack, err := next(ev)
+
+			if !keys[ev.IdempotencyKey()] {
+				keys[ev.IdempotencyKey()] = true
+				return nil, nil
+			}
+
+			return ack, err
+		},
+		rpc.DedupInterceptor(time.Minute),
+	)
+
+	rpc.Register(svc, func(ev *rpc.RecvMsgEvent, req *TestEchoACK) (interface{}, error) {
+		executeCount++
+		return &TestEchoACK{Msg: req.Msg, Value: req.Value}, nil
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	msgName := cellnet.MessageMetaByMsg(&TestEchoACK{}).FullName()
+	rpc.SetRetryPolicy(msgName, &rpc.RetryPolicy{
+		MaxAttempts:    3,
+		MinDelay:       time.Millisecond * 50,
+		AttemptTimeout: time.Millisecond * 300,
+	})
+	defer rpc.SetRetryPolicy(msgName, nil)
+
+	rpc_StartClientAt(retryRPC_Address, func(ev cellnet.Event) {
+		switch ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ses := ev.Session()
+
+			go func() {
+				ack, err := rpc.CallContext(context.Background(), ses, &TestEchoACK{Msg: "buy", Value: 1})
+				if err == nil && ack.(*TestEchoACK).Value == 1 {
+					signal.Done(1)
+				}
+
+				ack, err = rpc.CallSync(ses, &TestEchoACK{Msg: "buy", Value: 2}, time.Millisecond*300)
+				if err == nil && ack.(*TestEchoACK).Value == 2 {
+					signal.Done(2)
+				}
+
+				rpc.Call(ses, &TestEchoACK{Msg: "buy", Value: 3}, time.Millisecond*300, func(raw interface{}) {
+					if ack, ok := raw.(*TestEchoACK); ok && ack.Value == 3 {
+						signal.Done(3)
+					}
+				})
+
+				rpc.InvokeAsync[TestEchoACK, TestEchoACK](context.Background(), ses, &TestEchoACK{Msg: "buy", Value: 4}, func(ack *TestEchoACK, err error) {
+					if err == nil && ack.Value == 4 {
+						signal.Done(4)
+					}
+				})
+
+				// 流式请求不支持重试
+				if _, err := rpc.CallStream(context.Background(), ses, &TestEchoACK{}, 0); err != rpc.ErrRetryNotSupported {
+					t.Error("stream retry not rejected", err)
+				}
+			}()
+		}
+	})
+
+	signal.WaitAndExpect("rpc retry not work", 1, 2, 3, 4)
+
+	// 每次调用尝试两次，但只执行一次
+	cellnet.QueuedCall(serverQueue, func() {
+		if requestCount != 8 || executeCount != 4 || len(keys) != 4 {
+			t.Error("rpc dedup mismatch", requestCount, executeCount, len(keys))
+		}
+		signal.Done(5)
+	})
+
+	signal.WaitAndExpect("rpc dedup not work", 5)
+
+	// 重试次数用完后仍然回调错误
+	emptyPool := rpc.NewClientPool(nil)
+
+	rpc.Call(emptyPool, &TestEchoACK{}, time.Millisecond*300, func(raw interface{}) {
+		if raw == rpc.ErrEmptySession {
+			signal.Done(6)
+		}
+	})
+
+	rpc.CallContextAsync(context.Background(), emptyPool, &TestEchoACK{}, func(raw interface{}) {
+		if raw == rpc.ErrEmptySession {
+			signal.Done(7)
+		}
+	})
+
+	signal.WaitAndExpect("rpc retry error lost", 6, 7)
+
+	acceptor.Stop()
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+	_ "github.com/bobwong89757/cellnet/peer/kcp"
+	_ "github.com/bobwong89757/cellnet/peer/udp"
+	"github.com/bobwong89757/cellnet/proc"
+	_ "github.com/bobwong89757/cellnet/proc/kcp"
+	_ "github.com/bobwong89757/cellnet/proc/udp"
+	"github.com/bobwong89757/cellnet/relay"
+	"github.com/bobwong89757/cellnet/rpc"
+	"testing"
+	"time"
+)
+
+type transportContext struct {
+	Address   string
+	Protocol  string
+	Processor string
+}
+
+var transportContexts = []*transportContext{
+	{
+		Address:   "127.0.0.1:7711",
+		Protocol:  "kcp",
+		Processor: "kcp.ltv",
+	},
+	{
+		Address:   "127.0.0.1:7712",
+		Protocol:  "udp",
+		Processor: "udp.ltv",
+	},
+}
+
+// runTransportRPC 在指定的传输协议上测试 RPC 和 relay
+func runTransportRPC(t *testing.T, index int) {
+
+	ctx := transportContexts[index]
+
+	signal := NewSignalTester(t)
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer(ctx.Protocol+".Acceptor", ctx.Protocol+"server", ctx.Address, serverQueue)
+
+	// 用户回调不使用队列，广播函数停止处理的消息同样不能到达用户回调
+	removeBroadcaster := relay.AddBroadcaster(acceptor, func(ev *relay.RecvMsgEvent) bool {
+		if ev.Msg.(*TestEchoACK).Msg == "stop" {
+			signal.Done(4)
+			return false
+		}
+
+		return true
+	}, (*TestEchoACK)(nil))
+	defer removeBroadcaster()
+
+	proc.BindProcessorHandler(acceptor, ctx.Processor, func(ev cellnet.Event) {
+		switch ev := ev.(type) {
+		case *rpc.RecvMsgEvent:
+			msg := ev.Msg.(*TestEchoACK)
+			ev.Reply(&TestEchoACK{Msg: msg.Msg, Value: msg.Value})
+		case *relay.RecvMsgEvent:
+			if ev.Msg.(*TestEchoACK).Msg == "stop" {
+				t.Error("stopped relay message reached callback over " + ctx.Protocol)
+				return
+			}
+
+			// 原样转发回客户端
+			relay.Relay(ev.Session(), ev.Message(), ev.PassThroughAsInt64())
+		}
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer(ctx.Protocol+".Connector", ctx.Protocol+"client", ctx.Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, ctx.Processor, func(ev cellnet.Event) {
+		switch ev := ev.(type) {
+		case *relay.RecvMsgEvent:
+			if msg, ok := ev.Message().(*TestEchoACK); ok && msg.Value == 3 && ev.PassThroughAsInt64() == 100 {
+				signal.Done(3)
+			}
+		default:
+			if _, ok := ev.Message().(*cellnet.SessionConnected); !ok {
+				return
+			}
+
+			go func() {
+				ack, err := rpc.CallSync(connector, &TestEchoACK{Msg: "sync", Value: 1}, time.Second*5)
+				if err == nil && ack.(*TestEchoACK).Value == 1 {
+					signal.Done(1)
+				}
+
+				rpc.Call(connector, &TestEchoACK{Msg: "async", Value: 2}, time.Second*5, func(raw interface{}) {
+					if ack, ok := raw.(*TestEchoACK); ok && ack.Value == 2 {
+						signal.Done(2)
+					}
+				})
+
+				relay.Relay(connector, &TestEchoACK{Msg: "stop", Value: 4}, int64(100))
+				relay.Relay(connector, &TestEchoACK{Msg: "relay", Value: 3}, int64(100))
+			}()
+		}
+	})
+
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("rpc over "+ctx.Protocol+" not work", 1, 2, 3, 4)
+
+	connector.Stop()
+	acceptor.Stop()
+}
+
+func TestRPCOverKCP(t *testing.T) {
+
+	runTransportRPC(t, 0)
+}
+
+func TestRPCOverUDP(t *testing.T) {
+
+	runTransportRPC(t, 1)
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+	"github.com/bobwong89757/cellnet/proc"
+)
+
+const stateGate_Address = "127.0.0.1:16812"
+
+func TestStateGate(t *testing.T) {
+
+	signal := NewSignalTester(t)
+
+	gate := proc.NewStateGate("Connected")
+	gate.Allow(cellnet.MessageMetaByMsg(&TestEchoACK{}).FullName(), "Authenticated")
+	gate.SetMaxViolations(2)
+
+	// 第一次违规后模拟登录成功
+	gate.SetViolationNotify(func(ev cellnet.Event, state string) {
+		if state != "Connected" {
+			t.Error("state gate violation state mismatch", state)
+		}
+
+		if gate.SessionViolations(ev.Session()) == 1 {
+			gate.SetState(ev.Session(), "Authenticated")
+		}
+	})
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", stateGate_Address, serverQueue)
+
+	dispatcher := proc.NewMessageDispatcherBindPeer(acceptor, "tcp.ltv")
+	dispatcher.Use(gate.Middleware())
+
+	proc.Handle(dispatcher, func(ev cellnet.Event, msg *TestEchoACK) {
+		if msg.Msg == "a" {
+			t.Error("state gate violation reached handler")
+		}
+
+		// 模拟退出登录，之后的消息违规
+		gate.SetState(ev.Session(), "Connected")
+		ev.Session().Send(msg)
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", stateGate_Address, clientQueue)
+
+	proc.BindProcessorHandler(connector, "tcp.ltv", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionConnected:
+			ev.Session().Send(&TestEchoACK{Msg: "a"})
+			ev.Session().Send(&TestEchoACK{Msg: "b"})
+		case *TestEchoACK:
+			if msg.Msg == "b" {
+				signal.Done(1)
+				ev.Session().Send(&TestEchoACK{Msg: "c"})
+			}
+		case *cellnet.SessionClosed:
+			// 第二次违规被断开
+			signal.Done(2)
+		}
+	})
+
+	connector.(cellnet.TCPConnector).SetReconnectDuration(0)
+	connector.Start()
+	clientQueue.StartLoop()
+
+	signal.WaitAndExpect("state gate not work", 1, 2)
+
+	if gate.Violations() != 2 {
+		t.Error("state gate violations mismatch", gate.Violations())
+	}
+
+	connector.Stop()
+	acceptor.Stop()
+}
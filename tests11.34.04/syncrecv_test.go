@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bobwong89757/cellnet"
+	"github.com/bobwong89757/cellnet/peer"
+	"github.com/bobwong89757/cellnet/proc"
+)
+
+const syncRecv_Address = "127.0.0.1:16815"
+
+func TestSyncReceiver(t *testing.T) {
+
+	echoName := cellnet.MessageMetaByMsg(&TestEchoACK{}).FullName()
+
+	serverQueue := cellnet.NewEventQueue()
+	acceptor := peer.NewGenericPeer("tcp.Acceptor", "server", syncRecv_Address, serverQueue)
+
+	proc.BindProcessorHandler(acceptor, "tcp.ltv", func(ev cellnet.Event) {
+		switch msg := ev.Message().(type) {
+		case *cellnet.SessionAccepted:
+			ev.Session().Send(&TestEchoACK{Msg: "hello"})
+		case *TestEchoACK:
+			ev.Session().Send(msg)
+
+			if msg.Msg == "bye" {
+				ev.Session().Close()
+			}
+		}
+	})
+
+	acceptor.Start()
+	serverQueue.StartLoop()
+
+	clientQueue := cellnet.NewEventQueue()
+	connector := peer.NewGenericPeer("tcp.Connector", "client", syncRecv_Address, clientQueue)
+
+	rv := proc.NewSyncReceiver(connector)
+	proc.BindProcessorHandler(connector, "tcp.ltv", rv.EventCallback())
+
+	connector.(cellnet.TCPConnector).SetReconnectDuration(0)
+	connector.Start()
+	clientQueue.StartLoop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// 连接事件先到达，等待之后的消息时保留在缓存中
+	msg, err := rv.WaitMessage(ctx, echoName)
+	if err != nil || msg.(*TestEchoACK).Msg != "hello" {
+		t.Fatal("wait message failed", msg, err)
+	}
+
+	if _, err := rv.WaitMessage(ctx, "cellnet.SessionConnected"); err != nil {
+		t.Fatal("buffered message lost", err)
+	}
+
+	ses := connector.(cellnet.TCPConnector).Session()
+	ses.Send(&TestEchoACK{Msg: "a"})
+	ses.Send(&TestEchoACK{Msg: "b"})
+
+	ev, err := rv.WaitEvent(ctx, func(ev cellnet.Event) bool {
+		msg, ok := ev.Message().(*TestEchoACK)
+		return ok && msg.Msg == "b"
+	})
+
+	if err != nil || ev.Message().(*TestEchoACK).Msg != "b" {
+		t.Fatal("wait event failed", err)
+	}
+
+	if rv.Pending() != 1 {
+		t.Error("pending mismatch", rv.Pending())
+	}
+
+	err = rv.RecvContext(ctx, func(ev cellnet.Event) {
+		if ev.Message().(*TestEchoACK).Msg != "a" {
+			t.Error("recv order mismatch", ev.Message())
+		}
+	})
+
+	if err != nil {
+		t.Fatal("recv failed", err)
+	}
+
+	// 超时
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, time.Millisecond*50)
+	_, err = rv.WaitMessage(timeoutCtx, "cellnet.SessionClosed")
+	timeoutCancel()
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("wait message not timeout", err)
+	}
+
+	timeoutCtx, timeoutCancel = context.WithTimeout(ctx, time.Millisecond*50)
+	err = rv.RecvContext(timeoutCtx, func(ev cellnet.Event) {
+		t.Error("recv callback called after timeout", ev.Message())
+	})
+	timeoutCancel()
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("recv not timeout", err)
+	}
+
+	ses.Send(&TestEchoACK{Msg: "bye"})
+
+	msgs, err := rv.Expect(ctx, echoName, "cellnet.SessionClosed")
+	if err != nil || len(msgs) != 2 || msgs[0].(*TestEchoACK).Msg != "bye" {
+		t.Fatal("expect failed", msgs, err)
+	}
+
+	// 期望的消息顺序不符
+	rv.EventCallback()(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{Msg: "late"}})
+	rv.EventCallback()(&cellnet.RecvMsgEvent{Msg: &cellnet.SessionClosed{}})
+
+	if _, err := rv.Expect(ctx, "cellnet.SessionClosed", echoName); !errors.Is(err, proc.ErrUnexpectedMessage) {
+		t.Error("expect order not checked", err)
+	}
+
+	// 取出所有未处理的事件
+	if evs := rv.Drain(); len(evs) != 1 || rv.Pending() != 0 {
+		t.Error("drain mismatch", len(evs), rv.Pending())
+	}
+
+	// 缓存已满时丢弃最早到达的事件
+	rv.SetCapacity(2)
+
+	for _, text := range []string{"1", "2", "3"} {
+		rv.EventCallback()(&cellnet.RecvMsgEvent{Msg: &TestEchoACK{Msg: text}})
+	}
+
+	msg, err = rv.WaitMessage(ctx, echoName)
+	if err != nil || msg.(*TestEchoACK).Msg != "2" || rv.Pending() != 1 || rv.Dropped() != 1 {
+		t.Error("capacity mismatch", msg, err, rv.Pending(), rv.Dropped())
+	}
+
+	connector.Stop()
+	acceptor.Stop()
+}